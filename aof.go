@@ -0,0 +1,628 @@
+package eset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// the ops of the records in the append-only log,
+// each record is the uvarint length of the op and its arguments,
+// the op followed by its arguments:
+// the unix nano the element expires at, its ttl and the element for aofAdd,
+// the key for aofDel, and nothing for aofClear,
+// and then the big endian CRC-32C of the op and the arguments.
+const (
+	aofAdd byte = iota + 1
+	aofDel
+	aofClear
+)
+
+// the header of the log, which isn't compressed or encrypted,
+// its last byte is the version of the records:
+// the records of version 1 have no length,
+// and the logs written before the checksums were added have no header
+const (
+	aofMagic   = "EAOF"
+	aofVersion = 2
+	aofHeader  = aofMagic + "\x02"
+)
+
+var (
+	crcTable    = crc32.MakeTable(crc32.Castagnoli)
+	errChecksum = errors.New("eset: checksum mismatch in the append-only log")
+)
+
+// aofLog is the append-only log of a set.
+type aofLog struct {
+	path        string
+	file        *os.File
+	w           *bufio.Writer
+	// reused to encode the records
+	buf         []byte
+	// the writer encrypting the file if the set has WithSnapshotKey,
+	// which keeps counting the frames when the file is reopened
+	sealer      *sealWriter
+	// the bytes written to the log, including the buffered ones
+	size        int64
+	// the size of the log right after the last rewrite
+	baseSize    int64
+	rewriteSize int64
+	// the first error, the later records are dropped
+	err         error
+	// bumped by each rewrite, which moves the records in the file
+	rewrites    int
+	// the offset in the file and the size of the log
+	// when the previous auto snapshot was taken,
+	// 0 if none is taken since the last rewrite
+	snapshotOffset int64
+	snapshotSize   int64
+}
+
+
+// Appends every change of the set to a log file at path,
+// so that ReplayAOF can restore the set after a crash.
+// The log is rewritten with the current elements first,
+// so call ReplayAOF before it to restore the set from an existing log.
+// The records are written to the file each time the write lock is released,
+// without syncing it to disk, see SyncAOF.
+// Once the log exceeds rewriteSize, counted before compression,
+// and has doubled since the last rewrite,
+// it's rewritten under the lock to drop the stale records.
+// A rewriteSize less than or equal to 0 never rewrites it.
+// The log is closed by Close.
+func(es *Set[T]) EnableAOF(path string, rewriteSize int64) error {
+	es.lock()
+	defer es.unlock()
+
+	if es.expires == nil {
+		es.init()
+	}
+	if es.aof != nil {
+		es.aof.close()
+	}
+
+	es.aof = &aofLog{path: path, rewriteSize: rewriteSize}
+	if err := es.rewriteAOF(); err != nil {
+		es.aof = nil
+		return err
+	}
+
+	return nil
+}
+
+
+// Restores the set from a log written by EnableAOF,
+// the records are applied on top of the current elements.
+// The elements that expired since they were logged are skipped.
+// A record cut short by a crash at the end of the log,
+// or one that fails its checksum, ends the replay,
+// and the log is truncated before it, like Redis does,
+// a compressed or encrypted log is left as it is since EnableAOF rewrites it anyway.
+// An encrypted log ends with a frame written by Close,
+// if it's missing the records before are applied and io.ErrUnexpectedEOF is returned,
+// since the log may have been cut on purpose as well as by a crash.
+// It's fine if the log doesn't exist.
+func(es *Set[T]) ReplayAOF(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(aofHeader))
+	n, _ := io.ReadFull(file, header)
+	version := 0
+	start := int64(len(aofHeader))
+	if n == len(header) && string(header[:len(aofMagic)]) == aofMagic {
+		if version = int(header[len(aofMagic)]); version > aofVersion {
+			return fmt.Errorf("eset: unknown version %d of the append-only log", version)
+		}
+	} else {
+		start = 0
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	var src io.Reader = file
+	if es.aead != nil {
+		src = &openReader{r: file, aead: es.aead}
+	}
+	if es.decompress != nil {
+		dr, err := es.decompress(src)
+		if err == io.EOF {
+			// an empty log
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if closer, ok := dr.(io.Closer); ok {
+			defer closer.Close()
+		}
+		src = dr
+	}
+
+	r := &countingReader{r: bufio.NewReader(src)}
+	var valid int64
+	es.lock()
+	if es.expires == nil {
+		es.init()
+	}
+	now := es.nowNano()
+	for {
+		if err = es.replayRecord(r, now, version); err != nil {
+			break
+		}
+		valid = r.n
+	}
+	es.unlock()
+
+	if err == io.EOF && r.n == valid {
+		return nil
+	}
+	if es.aead != nil && errors.Is(err, io.ErrUnexpectedEOF) {
+		// the log may have been cut, see openReader
+		return io.ErrUnexpectedEOF
+	}
+	if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) || err == errChecksum {
+		if es.decompress != nil || es.aead != nil {
+			return nil
+		}
+		return os.Truncate(path, start + valid)
+	}
+
+	return err
+}
+
+
+// Reads a record and applies it to the set,
+// it's only applied once its checksum is verified if the log has them.
+// The checksum of a record of version 2 is verified before it's decoded,
+// since it knows its length.
+func(es *Set[T]) replayRecord(r *countingReader, now int64, version int) error {
+	if version >= 2 {
+		data, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		var sum [4]byte
+		if _, err := io.ReadFull(r, sum[:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if binary.BigEndian.Uint32(sum[:]) != crc32.Checksum(data, crcTable) {
+			return errChecksum
+		}
+
+		br := bytes.NewReader(data)
+		op, elem, b, err := es.readAOFOp(br)
+		if err == nil && br.Len() > 0 {
+			err = fmt.Errorf("eset: %d bytes left in a record of the append-only log", br.Len())
+		}
+		if err != nil {
+			return err
+		}
+		es.applyAOFOp(op, elem, b, now)
+		return nil
+	}
+
+	r.crc = 0
+	op, elem, b, err := es.readAOFOp(r)
+	if err != nil {
+		return err
+	}
+
+	if version == 1 {
+		sum := r.crc
+		var buf [4]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint32(buf[:]) != sum {
+			return errChecksum
+		}
+	}
+
+	es.applyAOFOp(op, elem, b, now)
+	return nil
+}
+
+
+// Decodes the op of a record and its arguments,
+// elem is the element for aofAdd and the key for aofDel.
+func(es *Set[T]) readAOFOp(r byteReader) (op byte, elem T, b base, err error) {
+	if op, err = r.ReadByte(); err != nil {
+		return
+	}
+
+	switch op {
+	case aofAdd:
+		if b.expireAt, err = binary.ReadVarint(r); err != nil {
+			return
+		}
+		var ttl int64
+		if ttl, err = binary.ReadVarint(r); err != nil {
+			return
+		}
+		b.ttl = time.Duration(ttl)
+		err = readElem(r, reflect.ValueOf(&elem).Elem())
+	case aofDel:
+		err = readElem(r, reflect.ValueOf(&elem).Elem())
+	case aofClear:
+	default:
+		err = fmt.Errorf("eset: unknown op %d in the append-only log", op)
+	}
+
+	return
+}
+
+
+func(es *Set[T]) applyAOFOp(op byte, elem T, b base, now int64) {
+	switch op {
+	case aofAdd:
+		if b.isExpired(now) {
+			es.del(es.keyOf(elem))
+		} else {
+			es.add(elem, b)
+		}
+	case aofDel:
+		es.del(elem)
+	case aofClear:
+		es.gen++
+		es.init()
+	}
+}
+
+
+// Writes the buffered records of the log to the file and syncs it to disk.
+// Returns the first error the log has run into since it's enabled,
+// the records after it are dropped.
+func(es *Set[T]) SyncAOF() error {
+	es.lock()
+	defer es.unlock()
+
+	if es.aof == nil {
+		return nil
+	}
+
+	es.flushAOF()
+	if es.aof.err == nil {
+		es.aof.err = es.aof.file.Sync()
+	}
+
+	return es.aof.err
+}
+
+
+// Appends a record for the change of an element,
+// it must be called while holding the write lock.
+func(es *Set[T]) logAOF(op byte, key T, b base) {
+	a := es.aof
+	if a.err != nil {
+		return
+	}
+
+	a.buf, a.err = es.appendAOFRecord(a.buf[:0], op, key, b)
+	if a.err != nil {
+		return
+	}
+
+	n, err := a.w.Write(a.buf)
+	a.size += int64(n)
+	a.err = err
+}
+
+
+func(es *Set[T]) appendAOFRecord(buf []byte, op byte, key T, b base) ([]byte, error) {
+	start := len(buf)
+	buf, err := es.appendAOFOp(buf, op, key, b)
+	if err != nil {
+		return buf, err
+	}
+
+	// the length goes before the op
+	end := len(buf)
+	var length [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(length[:], uint64(end - start))
+	buf = append(buf, length[:n]...)
+	copy(buf[start + n:], buf[start:end])
+	copy(buf[start:], length[:n])
+	return binary.BigEndian.AppendUint32(buf, crc32.Checksum(buf[start + n:], crcTable)), nil
+}
+
+
+func(es *Set[T]) appendAOFOp(buf []byte, op byte, key T, b base) ([]byte, error) {
+	buf = append(buf, op)
+	switch op {
+	case aofAdd:
+		buf = binary.AppendVarint(buf, b.expireAt)
+		buf = binary.AppendVarint(buf, int64(b.ttl))
+		elem := es.elemOf(key, b)
+		return appendElem(buf, reflect.ValueOf(&elem).Elem())
+	case aofDel:
+		return appendElem(buf, reflect.ValueOf(&key).Elem())
+	}
+
+	return buf, nil
+}
+
+
+// Writes the buffered records to the file,
+// and rewrites the log if it has grown too large.
+// It must be called while holding the write lock.
+func(es *Set[T]) flushAOF() {
+	a := es.aof
+	if a.err != nil {
+		return
+	}
+
+	if a.err = a.w.Flush(); a.err != nil {
+		return
+	}
+
+	if a.rewriteSize > 0 && a.size > a.rewriteSize && a.size >= 2 * a.baseSize {
+		a.err = es.rewriteAOF()
+	}
+}
+
+
+// Replaces the log with a record that clears the set
+// followed by the records that add the current elements,
+// so that replaying it on top of a snapshot doesn't bring back deleted elements.
+// It's written to a temporary file first and renamed over the log.
+// It must be called while holding the write lock.
+func(es *Set[T]) rewriteAOF() error {
+	a := es.aof
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path) + ".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(aofHeader); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	sealer := es.newSealer()
+	w := bufio.NewWriter(es.aofWriter(tmp, sealer))
+	var zero T
+	a.buf, _ = es.appendAOFRecord(a.buf[:0], aofClear, zero, base{})
+	w.Write(a.buf)
+	size := int64(len(a.buf))
+	now := es.nowNano()
+	for key, b := range es.elems {
+		if b.isExpired(now) {
+			continue
+		}
+
+		a.buf, err = es.appendAOFRecord(a.buf[:0], aofAdd, key, b)
+		if err == nil {
+			_, err = w.Write(a.buf)
+		}
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		size += int64(len(a.buf))
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), a.path); err != nil {
+		return err
+	}
+
+	a.rewrites++
+	a.sealer = sealer
+	a.snapshotOffset, a.snapshotSize = 0, 0
+	a.size, a.baseSize = size, size
+	return es.reopenAOF()
+}
+
+
+// Opens the log file again after it's replaced,
+// it must be called while holding the write lock.
+func(es *Set[T]) reopenAOF() error {
+	a := es.aof
+	file, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if a.file != nil {
+		a.file.Close()
+	}
+	a.file = file
+	if a.w == nil {
+		a.w = bufio.NewWriter(es.aofWriter(file, a.sealer))
+	} else {
+		a.w.Reset(es.aofWriter(file, a.sealer))
+	}
+
+	return nil
+}
+
+
+// Drops the records logged before the previous auto snapshot
+// once the one taken at offset of the log is saved,
+// so that the log only holds the changes since the previous snapshot,
+// which is what replaying it on top of either of the snapshots needs.
+// It must be called while holding the write lock.
+func(es *Set[T]) trimAOF(offset, size int64, rewrites int) {
+	a := es.aof
+	if a.err != nil || a.rewrites != rewrites {
+		return
+	}
+
+	prevOffset, prevSize := a.snapshotOffset, a.snapshotSize
+	if prevOffset > int64(len(aofHeader)) {
+		if err := es.cutAOF(prevOffset); err != nil {
+			// the log is intact, try again after the next snapshot
+			return
+		}
+		offset -= prevOffset - int64(len(aofHeader))
+		size -= prevSize
+		a.size -= prevSize
+		a.baseSize = max(a.baseSize - prevSize, 0)
+	}
+
+	a.snapshotOffset, a.snapshotSize = offset, size
+}
+
+
+// Replaces the log with the part of it from offset,
+// it must be called while holding the write lock.
+// The frames of an encrypted log are sealed again,
+// since their indexes start over in the new file.
+// The log is intact if an error is returned.
+func(es *Set[T]) cutAOF(offset int64) error {
+	a := es.aof
+	src, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	var index uint64
+	if a.sealer != nil {
+		if _, err := src.Seek(int64(len(aofHeader)), io.SeekStart); err != nil {
+			return err
+		}
+		if index, err = countFrames(src, offset); err != nil {
+			return err
+		}
+	} else if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path) + ".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	sealer := es.newSealer()
+	_, err = tmp.WriteString(aofHeader)
+	if err == nil && sealer != nil {
+		sealer.w = tmp
+		err = reseal(sealer, &openReader{r: src, aead: es.aead, index: index, unfinished: true})
+	} else if err == nil {
+		_, err = io.Copy(tmp, src)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), a.path); err != nil {
+		return err
+	}
+
+	if sealer != nil {
+		a.sealer = sealer
+	}
+	a.err = es.reopenAOF()
+	return nil
+}
+
+
+// Returns the writer of the log file,
+// which compresses each batch of records if the set has WithCompression,
+// and encrypts it with the sealer if the set has WithSnapshotKey.
+func(es *Set[T]) aofWriter(file *os.File, sealer *sealWriter) io.Writer {
+	var w io.Writer = file
+	if sealer != nil {
+		sealer.w = file
+		w = sealer
+	}
+	if es.compress != nil {
+		w = segmentWriter{w: w, compress: es.compress}
+	}
+
+	return w
+}
+
+
+// Returns the writer encrypting a new log file,
+// nil if the set has no WithSnapshotKey.
+func(es *Set[T]) newSealer() *sealWriter {
+	if es.aead == nil {
+		return nil
+	}
+
+	return &sealWriter{aead: es.aead}
+}
+
+
+// Writes the buffered records and closes the file,
+// an encrypted log is ended with its final frame.
+func(a *aofLog) close() error {
+	if a.err == nil {
+		a.err = a.w.Flush()
+	}
+	if a.err == nil && a.sealer != nil {
+		a.err = a.sealer.finish()
+	}
+	if err := a.file.Close(); a.err == nil {
+		a.err = err
+	}
+
+	return a.err
+}
+
+
+// countingReader counts the bytes read,
+// so that the end of the last complete record is known,
+// and checksums them for the current record.
+type countingReader struct {
+	r   *bufio.Reader
+	n   int64
+	crc uint32
+}
+
+
+func(r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	r.crc = crc32.Update(r.crc, crcTable, p[:n])
+	return n, err
+}
+
+
+func(r *countingReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.n++
+		r.crc = crc32.Update(r.crc, crcTable, []byte{b})
+	}
+
+	return b, err
+}
@@ -0,0 +1,212 @@
+package eset
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReplayAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.aof")
+	es := NewSet[string]()
+	if err := es.EnableAOF(path, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	es.Add("a")
+	es.AddWithExpire("b", time.Hour)
+	es.Add("c")
+	es.Remove("a")
+	es.AddWithExpire("d", time.Nanosecond)
+	if err := es.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond)
+	replayed := NewSet[string]()
+	if err := replayed.ReplayAOF(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sorted(replayed.GetAll()); !equalStrings(got, []string{"b", "c"}) {
+		t.Fatalf("replayed %v, want [b c]", got)
+	}
+	if _, ok := replayed.TTL("b"); !ok {
+		t.Fatal("b lost its ttl")
+	}
+	if _, ok := replayed.TTL("c"); ok {
+		t.Fatal("c got a ttl")
+	}
+}
+
+
+func TestReplayAOFTornWrite(t *testing.T) {
+	// each record is logged when the lock is released,
+	// so the size of the log after each Add ends a record
+	path := filepath.Join(t.TempDir(), "set.aof")
+	es := NewSet[string]()
+	if err := es.EnableAOF(path, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var ends []int64
+	for _, elem := range []string{"a", "b", "c"} {
+		es.Add(elem)
+		ends = append(ends, fileSize(t, path))
+	}
+	if err := es.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	intact, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		tear func([]byte) []byte
+		want []string
+		size int64
+	}{
+		{
+			name: "intact",
+			tear: func(data []byte) []byte { return data },
+			want: []string{"a", "b", "c"},
+			size: ends[2],
+		},
+		{
+			name: "last byte cut",
+			tear: func(data []byte) []byte { return data[:len(data)-1] },
+			want: []string{"a", "b"},
+			size: ends[1],
+		},
+		{
+			name: "cut in the middle of a record",
+			tear: func(data []byte) []byte { return data[:ends[1] + 2] },
+			want: []string{"a", "b"},
+			size: ends[1],
+		},
+		{
+			name: "cut at the end of a record",
+			tear: func(data []byte) []byte { return data[:ends[0]] },
+			want: []string{"a"},
+			size: ends[0],
+		},
+		{
+			name: "corrupted checksum",
+			tear: func(data []byte) []byte {
+				torn := append([]byte(nil), data...)
+				torn[len(torn)-1] ^= 0xff
+				return torn
+			},
+			want: []string{"a", "b"},
+			size: ends[1],
+		},
+		{
+			name: "corrupted length",
+			tear: func(data []byte) []byte {
+				torn := append([]byte(nil), data...)
+				torn[ends[1]] ^= 0x01
+				return torn
+			},
+			want: []string{"a", "b"},
+			size: ends[1],
+		},
+		{
+			name: "huge length",
+			tear: func(data []byte) []byte {
+				torn := binary.AppendUvarint(append([]byte(nil), data[:ends[1]]...), 1 << 62)
+				return append(torn, data[ends[1] + 1:]...)
+			},
+			want: []string{"a", "b"},
+			size: ends[1],
+		},
+		{
+			name: "corrupted element",
+			tear: func(data []byte) []byte {
+				torn := append([]byte(nil), data...)
+				torn[ends[0] + 1] ^= 0xff
+				return torn
+			},
+			want: []string{"a"},
+			size: ends[0],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, tt.tear(intact), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			replayed := NewSet[string]()
+			if err := replayed.ReplayAOF(path); err != nil {
+				t.Fatal(err)
+			}
+			if got := sorted(replayed.GetAll()); !equalStrings(got, tt.want) {
+				t.Fatalf("replayed %v, want %v", got, tt.want)
+			}
+			if size := fileSize(t, path); size != tt.size {
+				t.Fatalf("log truncated to %d bytes, want %d", size, tt.size)
+			}
+		})
+	}
+}
+
+
+func TestReplayAOFVersion1(t *testing.T) {
+	// the records of version 1 have no length
+	es := NewSet[string]()
+	record := func(op byte, elem string) []byte {
+		buf, err := es.appendAOFOp(nil, op, elem, base{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return binary.BigEndian.AppendUint32(buf, crc32.Checksum(buf, crcTable))
+	}
+	log := []byte("EAOF\x01")
+	log = append(log, record(aofAdd, "a")...)
+	log = append(log, record(aofAdd, "b")...)
+	log = append(log, record(aofDel, "a")...)
+	end := int64(len(log))
+	// a string claiming to be huge
+	log = binary.AppendUvarint(append(log, aofAdd, 0, 0), 1 << 62)
+
+	path := filepath.Join(t.TempDir(), "set.aof")
+	if err := os.WriteFile(path, log, 0644); err != nil {
+		t.Fatal(err)
+	}
+	replayed := NewSet[string]()
+	if err := replayed.ReplayAOF(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed.GetAll(); !equalStrings(got, []string{"b"}) {
+		t.Fatalf("replayed %v, want [b]", got)
+	}
+	if size := fileSize(t, path); size != end {
+		t.Fatalf("log truncated to %d bytes, want %d", size, end)
+	}
+}
+
+
+func TestReplayAOFMissing(t *testing.T) {
+	es := NewSet[string]()
+	if err := es.ReplayAOF(filepath.Join(t.TempDir(), "missing.aof")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return info.Size()
+}
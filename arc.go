@@ -0,0 +1,158 @@
+package eset
+
+import "sync"
+
+// arc is the adaptive replacement cache policy.
+// The resident elements are split between a list of the elements
+// seen once recently and a list of the elements seen more than once,
+// and the evicted elements are remembered in a ghost list for each.
+// A hit in a ghost list means that its list was too small,
+// so the target size of the recent list is adapted to it.
+type arc[T comparable] struct {
+	mutex    sync.Mutex
+	capacity int
+	// the target size of the recent list
+	target   int
+	lists    [4]*linkedList[T]
+	entries  map[T]*arcEntry[T]
+	// the element returned by the last call of victim,
+	// it goes to a ghost list if it's the next one removed
+	evicting *T
+}
+
+type arcEntry[T comparable] struct {
+	list int
+	node *node[T]
+}
+
+const (
+	arcRecent = iota
+	arcFrequent
+	arcRecentGhost
+	arcFrequentGhost
+)
+
+
+// A non-positive capacity means that the set is limited by weight,
+// then the number of resident elements is used instead.
+func newARC[T comparable](capacity int) *arc[T] {
+	a := &arc[T]{
+		capacity: capacity,
+		entries:  make(map[T]*arcEntry[T]),
+	}
+	for i := range a.lists {
+		a.lists[i] = newLinkedList[T]()
+	}
+
+	return a
+}
+
+
+func(a *arc[T]) add(key T) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	entry, isExist := a.entries[key]
+	if !isExist {
+		a.entries[key] = &arcEntry[T]{list: arcRecent, node: a.lists[arcRecent].pushFront(key)}
+		a.trimGhosts()
+		return
+	}
+
+	switch entry.list {
+	case arcRecentGhost:
+		a.target = min(a.target + max(a.lists[arcFrequentGhost].len / a.lists[arcRecentGhost].len, 1), a.size())
+	case arcFrequentGhost:
+		a.target = max(a.target - max(a.lists[arcRecentGhost].len / a.lists[arcFrequentGhost].len, 1), 0)
+	}
+
+	a.move(entry, arcFrequent)
+}
+
+
+func(a *arc[T]) access(key T) {
+	a.mutex.Lock()
+	if entry, isExist := a.entries[key]; isExist && entry.list <= arcFrequent {
+		a.move(entry, arcFrequent)
+	}
+	a.mutex.Unlock()
+}
+
+
+func(a *arc[T]) remove(key T) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	evicting := a.evicting
+	a.evicting = nil
+	entry, isExist := a.entries[key]
+	if !isExist || entry.list > arcFrequent {
+		return
+	}
+
+	if evicting != nil && *evicting == key {
+		a.move(entry, entry.list + arcRecentGhost)
+		a.trimGhosts()
+		return
+	}
+
+	a.lists[entry.list].remove(entry.node)
+	delete(a.entries, key)
+}
+
+
+func(a *arc[T]) victim() (key T, ok bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	list := arcFrequent
+	if recent := a.lists[arcRecent].len; recent > 0 && (recent > a.target || a.lists[arcFrequent].len == 0) {
+		list = arcRecent
+	}
+
+	n := a.lists[list].back()
+	if n == nil {
+		return key, false
+	}
+
+	a.evicting = &n.key
+	return n.key, true
+}
+
+
+func(a *arc[T]) move(entry *arcEntry[T], list int) {
+	a.lists[entry.list].remove(entry.node)
+	a.lists[list].insertFront(entry.node)
+	entry.list = list
+}
+
+
+// Forgets the oldest evicted elements,
+// so that the recent lists hold at most capacity elements
+// and all the lists hold at most twice of it.
+func(a *arc[T]) trimGhosts() {
+	size := a.size()
+	for a.lists[arcRecentGhost].len > 0 && a.lists[arcRecent].len + a.lists[arcRecentGhost].len > size {
+		a.forget(arcRecentGhost)
+	}
+
+	for a.lists[arcFrequentGhost].len > 0 && len(a.entries) > 2 * size {
+		a.forget(arcFrequentGhost)
+	}
+}
+
+
+func(a *arc[T]) forget(list int) {
+	n := a.lists[list].back()
+	a.lists[list].remove(n)
+	delete(a.entries, n.key)
+}
+
+
+func(a *arc[T]) size() int {
+	if a.capacity > 0 {
+		return a.capacity
+	}
+
+	return a.lists[arcRecent].len + a.lists[arcFrequent].len
+}
@@ -0,0 +1,128 @@
+// Package badgerstore is an eset.Store of strings on top of Badger,
+// e.g. for a set that outgrows the memory:
+//
+//	store := badgerstore.New(db, "sessions/")
+//	es := eset.NewSet[string](eset.WithStore[string](store), eset.WithMaxSize(1e6))
+package badgerstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// Store keeps the elements as keys under a prefix,
+// and the unix nano they expire at as the values, 0 if they have no ttl.
+// The keys are given the ttl of the elements too,
+// so that Badger drops the expired ones during compactions.
+type Store struct {
+	db     *badger.DB
+	prefix []byte
+}
+
+
+func New(db *badger.DB, prefix string) *Store {
+	return &Store{db: db, prefix: []byte(prefix)}
+}
+
+
+func(s *Store) Get(elem string) (deadline time.Time, ok bool, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(s.key(elem))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		ok = true
+		return item.Value(func(value []byte) error {
+			deadline = decodeDeadline(value)
+			return nil
+		})
+	})
+
+	return deadline, ok, err
+}
+
+
+func(s *Store) Put(elem string, deadline time.Time) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry(s.key(elem), encodeDeadline(deadline))
+		if !deadline.IsZero() {
+			ttl := time.Until(deadline)
+			if ttl <= 0 {
+				return txn.Delete(s.key(elem))
+			}
+			entry = entry.WithTTL(ttl)
+		}
+		return txn.SetEntry(entry)
+	})
+}
+
+
+func(s *Store) Delete(elem string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(s.key(elem))
+	})
+}
+
+
+// A read transaction is open while fn runs.
+func(s *Store) Iterate(fn func(elem string, deadline time.Time) bool) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = s.prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			var deadline time.Time
+			err := item.Value(func(value []byte) error {
+				deadline = decodeDeadline(value)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if !fn(string(item.Key()[len(s.prefix):]), deadline) {
+				return nil
+			}
+		}
+
+		return nil
+	})
+}
+
+
+func(s *Store) key(elem string) []byte {
+	return append(s.prefix[:len(s.prefix):len(s.prefix)], elem...)
+}
+
+
+func encodeDeadline(deadline time.Time) []byte {
+	var value [8]byte
+	if !deadline.IsZero() {
+		binary.BigEndian.PutUint64(value[:], uint64(deadline.UnixNano()))
+	}
+
+	return value[:]
+}
+
+
+func decodeDeadline(value []byte) time.Time {
+	if len(value) != 8 {
+		return time.Time{}
+	}
+
+	nano := int64(binary.BigEndian.Uint64(value))
+	if nano == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nano)
+}
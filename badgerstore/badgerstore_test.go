@@ -0,0 +1,53 @@
+package badgerstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ichxxx/eset"
+)
+
+func TestStore(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := New(db, "set:")
+	// the elements under other prefixes aren't iterated
+	New(db, "other:").Put("x", time.Time{})
+	deadline := time.Now().Add(time.Hour).Truncate(0)
+	store.Put("a", time.Time{})
+	store.Put("b", deadline)
+	store.Put("c", time.Time{})
+	store.Delete("c")
+
+	if got, ok, err := store.Get("b"); err != nil || !ok || !got.Equal(deadline) {
+		t.Fatalf("Get(b) = %v, %v, %v, want %v", got, ok, err, deadline)
+	}
+	if got, ok, _ := store.Get("a"); !ok || !got.IsZero() {
+		t.Fatalf("Get(a) = %v, %v, want no deadline", got, ok)
+	}
+	if _, ok, _ := store.Get("c"); ok {
+		t.Fatal("deleted element is stored")
+	}
+
+	var elems []string
+	store.Iterate(func(elem string, _ time.Time) bool {
+		elems = append(elems, elem)
+		return true
+	})
+	if len(elems) != 2 || elems[0] != "a" || elems[1] != "b" {
+		t.Fatalf("iterated %v, want [a b]", elems)
+	}
+
+	es := eset.NewSet[string](eset.WithStore[string](store))
+	if !es.Contains("b") {
+		t.Fatal("set doesn't load the element from the store")
+	}
+	if ttl, ok := es.TTL("b"); !ok || ttl <= 59 * time.Minute {
+		t.Fatalf("ttl of b = %v, %v, want about an hour", ttl, ok)
+	}
+}
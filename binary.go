@@ -0,0 +1,295 @@
+package eset
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// the version of the layout written by MarshalBinary
+const binaryVersion = 1
+
+// the most readBytes allocates up front from a reader of unknown length
+const readChunkSize = 64 << 10
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+
+// Encodes the unexpired elements in a compact binary layout:
+// a version byte, the number of elements as a uvarint,
+// then for each element the unix nano it expires at as a varint,
+// 0 if it has no ttl, followed by the element.
+// Strings are prefixed with their length, integers are varints,
+// and types implementing encoding.BinaryMarshaler are prefixed with the length of their encoding.
+// The elements of an ExpirableSet are prefixed with their kind,
+// and only the predeclared types are supported for them.
+func(es *Set[T]) MarshalBinary() ([]byte, error) {
+	return marshalEntries(es.Entries())
+}
+
+
+func marshalEntries[T comparable](entries []Entry[T]) ([]byte, error) {
+	buf := make([]byte, 0, 1 + binary.MaxVarintLen64 + len(entries) * 16)
+	buf = append(buf, binaryVersion)
+	buf = binary.AppendUvarint(buf, uint64(len(entries)))
+
+	var err error
+	for _, entry := range entries {
+		buf = binary.AppendVarint(buf, unixNano(entry.ExpireAt))
+		buf, err = appendElem(buf, reflect.ValueOf(&entry.Elem).Elem())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+
+// Replaces the elements of the set with the ones
+// encoded by MarshalBinary, the options of the set are kept.
+func(es *Set[T]) UnmarshalBinary(data []byte) error {
+	entries, err := decodeBinary[T](data)
+	if err != nil {
+		return err
+	}
+
+	es.replaceEntries(entries)
+	return nil
+}
+
+
+func decodeBinary[T comparable](data []byte) ([]Entry[T], error) {
+	r := bytes.NewReader(data)
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if version != binaryVersion {
+		return nil, ErrBinaryVersion
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// every entry takes at least 2 bytes
+	entries := make([]Entry[T], 0, min(count, uint64(r.Len() / 2)))
+	for i := uint64(0); i < count; i++ {
+		var entry Entry[T]
+		expireAt, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if expireAt != 0 {
+			entry.ExpireAt = time.Unix(0, expireAt)
+		}
+
+		if err := readElem(r, reflect.ValueOf(&entry.Elem).Elem()); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+
+func appendElem(buf []byte, v reflect.Value) ([]byte, error) {
+	if isBinaryType(v.Type()) {
+		data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(data)))
+		return append(buf, data...), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		buf = binary.AppendUvarint(buf, uint64(v.Len()))
+		return append(buf, v.String()...), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.AppendVarint(buf, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.AppendUvarint(buf, v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.Float())), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, fmt.Errorf("%w: nil", ErrUnsupportedType)
+		}
+
+		elem := v.Elem()
+		if _, ok := predeclaredType(elem.Kind()); !ok || elem.Type().PkgPath() != "" {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, elem.Type())
+		}
+		return appendElem(append(buf, byte(elem.Kind())), elem)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, v.Type())
+}
+
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+
+// Decodes an element into v, which must be settable.
+func readElem(r byteReader, v reflect.Value) error {
+	if isBinaryType(v.Type()) {
+		data, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		data, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(data))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var bits [8]byte
+		if _, err := io.ReadFull(r, bits[:]); err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(bits[:])))
+	case reflect.Bool:
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b != 0)
+	case reflect.Interface:
+		kind, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		typ, ok := predeclaredType(reflect.Kind(kind))
+		if !ok {
+			return fmt.Errorf("%w: kind %d", ErrUnsupportedType, kind)
+		}
+
+		elem := reflect.New(typ).Elem()
+		if err := readElem(r, elem); err != nil {
+			return err
+		}
+		v.Set(elem)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, v.Type())
+	}
+
+	return nil
+}
+
+
+// Returns true if the type encodes itself,
+// it must implement both encoding.BinaryMarshaler and encoding.BinaryUnmarshaler.
+func isBinaryType(typ reflect.Type) bool {
+	return typ.Kind() != reflect.Interface &&
+		typ.Implements(binaryMarshalerType) &&
+		reflect.PointerTo(typ).Implements(binaryUnmarshalerType)
+}
+
+
+// Reads the data prefixed by its uvarint length.
+// The length may be corrupted, so it's only allocated up front
+// if r is known to have that many bytes or it's small,
+// otherwise the data is read as it comes.
+func readBytes(r byteReader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if br, ok := r.(*bytes.Reader); ok && n > uint64(br.Len()) || n > math.MaxInt64 {
+		return nil, io.ErrUnexpectedEOF
+	} else if !ok && n > readChunkSize {
+		var buf bytes.Buffer
+		if copied, err := io.CopyN(&buf, r, int64(n)); copied < int64(n) {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	data := make([]byte, n)
+	if _, err = io.ReadFull(r, data); err == io.EOF && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return data, err
+}
+
+
+// Returns the predeclared type of the kind,
+// which the elements of an ExpirableSet are decoded into.
+func predeclaredType(kind reflect.Kind) (reflect.Type, bool) {
+	switch kind {
+	case reflect.String:
+		return reflect.TypeOf(""), true
+	case reflect.Int:
+		return reflect.TypeOf(int(0)), true
+	case reflect.Int8:
+		return reflect.TypeOf(int8(0)), true
+	case reflect.Int16:
+		return reflect.TypeOf(int16(0)), true
+	case reflect.Int32:
+		return reflect.TypeOf(int32(0)), true
+	case reflect.Int64:
+		return reflect.TypeOf(int64(0)), true
+	case reflect.Uint:
+		return reflect.TypeOf(uint(0)), true
+	case reflect.Uint8:
+		return reflect.TypeOf(uint8(0)), true
+	case reflect.Uint16:
+		return reflect.TypeOf(uint16(0)), true
+	case reflect.Uint32:
+		return reflect.TypeOf(uint32(0)), true
+	case reflect.Uint64:
+		return reflect.TypeOf(uint64(0)), true
+	case reflect.Uintptr:
+		return reflect.TypeOf(uintptr(0)), true
+	case reflect.Float32:
+		return reflect.TypeOf(float32(0)), true
+	case reflect.Float64:
+		return reflect.TypeOf(float64(0)), true
+	case reflect.Bool:
+		return reflect.TypeOf(false), true
+	}
+
+	return nil, false
+}
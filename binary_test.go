@@ -0,0 +1,90 @@
+package eset
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestBinary(t *testing.T) {
+	es := NewSet[string]()
+	es.Add("a")
+	es.AddWithExpire("b", time.Hour)
+
+	data, err := es.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := NewSet[string]()
+	decoded.Add("c")
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := sorted(decoded.GetAll()); !equalStrings(got, []string{"a", "b"}) {
+		t.Fatalf("decoded %v, want [a b]", got)
+	}
+	if _, ok := decoded.TTL("b"); !ok {
+		t.Fatal("b lost its ttl")
+	}
+
+	for i := 1; i < len(data); i++ {
+		if err := NewSet[string]().UnmarshalBinary(data[:i]); err == nil {
+			t.Fatalf("decoded %d of %d bytes", i, len(data))
+		}
+	}
+	if err := decoded.UnmarshalBinary(append([]byte{9}, data[1:]...)); !errors.Is(err, ErrBinaryVersion) {
+		t.Fatalf("error = %v, want ErrBinaryVersion", err)
+	}
+}
+
+
+func TestBinaryTypes(t *testing.T) {
+	ints := NewSet[int]()
+	ints.AddAll(-1, 0, 1 << 40)
+	data, err := ints.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedInts := NewSet[int]()
+	if err := decodedInts.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := sortedInts(decodedInts.GetAll()); !equalInts(got, []int{-1, 0, 1 << 40}) {
+		t.Fatalf("decoded %v", got)
+	}
+
+	// types implementing encoding.BinaryMarshaler encode themselves
+	addrs := NewSet[netip.Addr]()
+	addrs.Add(netip.MustParseAddr("10.0.0.1"))
+	data, err = addrs.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedAddrs := NewSet[netip.Addr]()
+	if err := decodedAddrs.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !decodedAddrs.Contains(netip.MustParseAddr("10.0.0.1")) {
+		t.Fatalf("decoded %v", decodedAddrs.GetAll())
+	}
+
+	boxed := New()
+	boxed.AddAll("a", 1, 2.5, true)
+	data, err = boxed.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedBoxed := New()
+	if err := decodedBoxed.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decodedBoxed.Size() != 4 || !decodedBoxed.Contains(2.5) || !decodedBoxed.Contains(true) {
+		t.Fatalf("decoded %v", decodedBoxed.GetAll())
+	}
+
+	boxed.Add(point{1, 2})
+	if _, err := boxed.MarshalBinary(); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("error = %v, want ErrUnsupportedType", err)
+	}
+}
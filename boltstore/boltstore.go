@@ -0,0 +1,107 @@
+// Package boltstore is an eset.Store of strings on top of bbolt,
+// e.g. for a set that outgrows the memory:
+//
+//	store, err := boltstore.New(db, "sessions")
+//	es := eset.NewSet[string](eset.WithStore[string](store), eset.WithMaxSize(1e6))
+package boltstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// returned from ForEach to stop the iteration
+var errStop = errors.New("stop")
+
+// Store keeps the elements as the keys of a bucket,
+// and the unix nano they expire at as the values, 0 if they have no ttl.
+type Store struct {
+	db     *bbolt.DB
+	bucket []byte
+}
+
+
+// Returns a store on the bucket, which is created if it doesn't exist.
+func New(db *bbolt.DB, bucket string) (*Store, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{db: db, bucket: []byte(bucket)}, nil
+}
+
+
+func(s *Store) Get(elem string) (deadline time.Time, ok bool, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(s.bucket).Get([]byte(elem))
+		if value != nil {
+			deadline, ok = decodeDeadline(value), true
+		}
+		return nil
+	})
+
+	return deadline, ok, err
+}
+
+
+func(s *Store) Put(elem string, deadline time.Time) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(s.bucket).Put([]byte(elem), encodeDeadline(deadline))
+	})
+}
+
+
+func(s *Store) Delete(elem string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete([]byte(elem))
+	})
+}
+
+
+// A read transaction is open while fn runs.
+func(s *Store) Iterate(fn func(elem string, deadline time.Time) bool) error {
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(s.bucket).ForEach(func(key, value []byte) error {
+			if !fn(string(key), decodeDeadline(value)) {
+				return errStop
+			}
+			return nil
+		})
+	})
+	if err == errStop {
+		return nil
+	}
+
+	return err
+}
+
+
+func encodeDeadline(deadline time.Time) []byte {
+	var value [8]byte
+	if !deadline.IsZero() {
+		binary.BigEndian.PutUint64(value[:], uint64(deadline.UnixNano()))
+	}
+
+	return value[:]
+}
+
+
+func decodeDeadline(value []byte) time.Time {
+	if len(value) != 8 {
+		return time.Time{}
+	}
+
+	nano := int64(binary.BigEndian.Uint64(value))
+	if nano == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nano)
+}
@@ -0,0 +1,113 @@
+// Package byteset provides an expirable set of byte slices.
+// Byte slices aren't comparable, so they are converted
+// to strings internally and copied back when they are read.
+package byteset
+
+import (
+	"time"
+
+	"github.com/ichxxx/eset"
+)
+
+type Set struct {
+	set *eset.Set[string]
+}
+
+
+func New(opts ...eset.Option) *Set {
+	return &Set{
+		set: eset.NewSet[string](opts...),
+	}
+}
+
+
+// Add an element to the set normally.
+func(bs *Set) Add(elem []byte) {
+	bs.set.Add(string(elem))
+}
+
+
+// Add an element to the set with an expiration time.
+func(bs *Set) AddWithExpire(elem []byte, expireTime time.Duration) {
+	bs.set.AddWithExpire(string(elem), expireTime)
+}
+
+
+func(bs *Set) Contains(elem []byte) bool {
+	return bs.set.Contains(string(elem))
+}
+
+
+// Remove an element in the set.
+// Returns false if the element doesn't exist.
+func(bs *Set) Remove(elem []byte) bool {
+	return bs.set.Remove(string(elem))
+}
+
+
+// Get ttl of the element,
+// see eset.Set.GetElemTTL.
+func(bs *Set) GetElemTTL(elem []byte) (float64, error) {
+	return bs.set.GetElemTTL(string(elem))
+}
+
+
+// Returns the remaining time to live of the element,
+// see eset.Set.TTL.
+func(bs *Set) TTL(elem []byte) (time.Duration, bool) {
+	return bs.set.TTL(string(elem))
+}
+
+
+// Returns a slice that has all unexpired elements.
+func(bs *Set) GetAll() [][]byte {
+	elems := bs.set.GetAll()
+	all := make([][]byte, 0, len(elems))
+	for _, elem := range elems {
+		all = append(all, []byte(elem))
+	}
+
+	return all
+}
+
+
+// Appends all unexpired elements to dst and returns the extended slice,
+// see eset.Set.AppendTo.
+func(bs *Set) AppendTo(dst [][]byte) [][]byte {
+	bs.set.ForEach(func(elem string) {
+		dst = append(dst, []byte(elem))
+	})
+	return dst
+}
+
+
+// Do something for each elements in the set.
+func(bs *Set) ForEach(handler func([]byte)) {
+	bs.set.ForEach(func(elem string) {
+		handler([]byte(elem))
+	})
+}
+
+
+func(bs *Set) Size() int {
+	return bs.set.Size()
+}
+
+
+func(bs *Set) Clear() {
+	bs.set.Clear()
+}
+
+
+// Stops the background goroutines of the set, like its janitor,
+// see eset.Set.Stop.
+func(bs *Set) Stop() {
+	bs.set.Stop()
+}
+
+
+// Stops the set and releases what it holds,
+// see eset.Set.Close.
+func(bs *Set) Close() error {
+	return bs.set.Close()
+}
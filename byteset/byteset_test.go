@@ -0,0 +1,72 @@
+package byteset
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ichxxx/eset"
+)
+
+func TestSet(t *testing.T) {
+	bs := New()
+	elem := []byte("foo")
+	bs.Add(elem)
+	bs.Add([]byte("foo"))
+	bs.AddWithExpire([]byte("bar"), time.Hour)
+	bs.AddWithExpire([]byte("baz"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	// the set doesn't keep the slice that was added
+	elem[0] = 'g'
+	if !bs.Contains([]byte("foo")) || bs.Contains(elem) || bs.Contains([]byte("baz")) {
+		t.Fatal("wrong elements")
+	}
+	if bs.Size() != 2 {
+		t.Fatalf("size = %d, want 2", bs.Size())
+	}
+	if _, err := bs.GetElemTTL([]byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+
+	all := bs.GetAll()
+	sort.Slice(all, func(i, j int) bool {
+		return bytes.Compare(all[i], all[j]) < 0
+	})
+	if len(all) != 2 || string(all[0]) != "bar" || string(all[1]) != "foo" {
+		t.Fatalf("GetAll() = %q, want [bar foo]", all)
+	}
+	// the returned slices are copies
+	all[0][0] = 'x'
+	if !bs.Contains([]byte("bar")) {
+		t.Fatal("changing a returned slice changed the set")
+	}
+
+	bs.Remove([]byte("foo"))
+	bs.Clear()
+	if bs.Size() != 0 {
+		t.Fatalf("size = %d after clear, want 0", bs.Size())
+	}
+}
+
+
+func TestOptions(t *testing.T) {
+	bs := New(eset.WithMaxSize(2))
+	bs.Add([]byte("a"))
+	bs.Add([]byte("b"))
+	bs.Add([]byte("c"))
+	if bs.Size() != 2 || bs.Contains([]byte("a")) {
+		t.Fatalf("has %q, want [b c]", bs.GetAll())
+	}
+}
+
+
+func TestClose(t *testing.T) {
+	bs := New(eset.WithJanitor(time.Millisecond))
+	bs.AddWithExpire([]byte("a"), time.Millisecond)
+	bs.Stop()
+	if err := bs.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package cborset encodes expirable sets with CBOR,
+// it's a separate package so that eset doesn't depend on a CBOR library.
+// A set is encoded as an array of [elem, expireAt] pairs,
+// expireAt is the unix nano the element expires at, 0 if it has no ttl.
+package cborset
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/ichxxx/eset"
+)
+
+// Set wraps a set so that it can be a field of a struct
+// encoded with CBOR.
+type Set[T comparable] struct {
+	*eset.Set[T]
+}
+
+// pair is the encoding of an element,
+// the toarray option encodes it as a CBOR array instead of a map.
+type pair[T comparable] struct {
+	_        struct{} `cbor:",toarray"`
+	Elem     T
+	ExpireAt int64
+}
+
+
+// Encodes the unexpired elements of the set.
+func Marshal[T comparable](es *eset.Set[T]) ([]byte, error) {
+	return cbor.Marshal(Set[T]{es})
+}
+
+
+// Returns a set that has the decoded elements,
+// opts are applied to it.
+// The elements of an ExpirableSet are decoded into
+// the types CBOR picks for interface{}, e.g. uint64 for positive integers,
+// and the arrays and maps, which aren't hashable, fail it with eset.ErrUnhashable.
+func Unmarshal[T comparable](data []byte, opts ...eset.Option) (*eset.Set[T], error) {
+	entries, err := decodeEntries[T](data)
+	if err != nil {
+		return nil, err
+	}
+
+	return eset.FromEntries(entries, opts...), nil
+}
+
+
+func(s Set[T]) MarshalCBOR() ([]byte, error) {
+	if s.Set == nil {
+		return cbor.Marshal(nil)
+	}
+
+	entries := s.Entries()
+	pairs := make([]pair[T], len(entries))
+	for i, entry := range entries {
+		pairs[i].Elem = entry.Elem
+		if !entry.ExpireAt.IsZero() {
+			pairs[i].ExpireAt = entry.ExpireAt.UnixNano()
+		}
+	}
+
+	return cbor.Marshal(pairs)
+}
+
+
+// Decodes into a new set without options.
+func(s *Set[T]) UnmarshalCBOR(data []byte) error {
+	entries, err := decodeEntries[T](data)
+	if err != nil {
+		return err
+	}
+
+	s.Set = eset.FromEntries(entries)
+	return nil
+}
+
+
+func decodeEntries[T comparable](data []byte) ([]eset.Entry[T], error) {
+	var pairs []pair[T]
+	if err := cbor.Unmarshal(data, &pairs); err != nil {
+		return nil, err
+	}
+
+	entries := make([]eset.Entry[T], len(pairs))
+	for i, p := range pairs {
+		if !reflect.ValueOf(&p.Elem).Elem().Comparable() {
+			return nil, fmt.Errorf("cborset: %w: %T", eset.ErrUnhashable, p.Elem)
+		}
+		entries[i].Elem = p.Elem
+		if p.ExpireAt != 0 {
+			entries[i].ExpireAt = time.Unix(0, p.ExpireAt)
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,24 @@
+package eset
+
+import "time"
+
+func(es *Set[T]) startClock() {
+	es.clock.Store(time.Now().UnixNano())
+	go es.runClock(es.clockPrecision)
+}
+
+
+func(es *Set[T]) runClock(precision time.Duration) {
+	ticker := time.NewTicker(precision)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			es.clock.Store(now.UnixNano())
+		case <-es.stop:
+			es.clock.Store(0)
+			return
+		}
+	}
+}
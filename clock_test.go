@@ -0,0 +1,27 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoarseClock(t *testing.T) {
+	es := NewSet[int](WithCoarseClock(10 * time.Millisecond))
+	es.AddWithExpire(1, time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for es.Peek(1) {
+		if time.Now().After(deadline) {
+			t.Fatal("element didn't expire on the coarse clock")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// the set falls back to the precise clock once stopped
+	es.Stop()
+	es.AddWithExpire(2, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if es.Peek(2) {
+		t.Fatal("stopped set still reads the coarse clock")
+	}
+}
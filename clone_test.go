@@ -0,0 +1,208 @@
+package eset
+
+import (
+	"bytes"
+	"encoding/gob"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCloneBounded(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		// the most elements the clone can hold
+		max int
+	}{
+		{"max size", []Option{WithMaxSize(2)}, 2},
+		{"max size with LFU", []Option{WithMaxSize(2), WithEvictionPolicy(LFU)}, 2},
+		{"max size with ARC", []Option{WithMaxSize(2), WithEvictionPolicy(ARC)}, 2},
+		{"max weight", []Option{WithMaxWeight(3)}, 3},
+		{"max weight with weigher", []Option{WithMaxWeight(4), WithWeigher(func(interface{}) int64 { return 2 })}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := NewSet[int](tt.opts...)
+			es.AddAll(1, 2)
+
+			clone := es.Clone()
+			for i := 3; i < 10; i++ {
+				clone.Add(i)
+			}
+			if clone.Len() != tt.max {
+				t.Fatalf("clone has %d elements, want %d", clone.Len(), tt.max)
+			}
+			if !clone.Contains(9) {
+				t.Fatal("clone doesn't have the last added element")
+			}
+			if es.Len() != 2 || !es.Contains(1) || !es.Contains(2) {
+				t.Fatalf("original changed to %v", es.GetAll())
+			}
+		})
+	}
+}
+
+
+func TestCloneKeepsTTL(t *testing.T) {
+	es := NewSet[string](WithDefaultTTL(time.Hour))
+	es.Add("persisted")
+	es.Persist("persisted")
+	es.AddWithExpire("short", time.Minute)
+	es.AddWithExpire("expired", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	clone := es.Clone()
+	checkRestoredTTL(t, clone)
+	if clone.Contains("expired") {
+		t.Fatal("clone has the expired element")
+	}
+
+	clone.Add("new")
+	if ttl, ok := clone.TTL("new"); !ok || ttl > time.Hour {
+		t.Fatalf("new element of the clone has ttl %v, want the default ttl", ttl)
+	}
+}
+
+
+// restorers restore the elements of src into a set created with opts.
+var restorers = []struct {
+	name    string
+	restore func(t *testing.T, src *Set[string], opts []Option) *Set[string]
+}{
+	{"gob", func(t *testing.T, src *Set[string], opts []Option) *Set[string] {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(src); err != nil {
+			t.Fatal(err)
+		}
+		dst := NewSet[string](opts...)
+		if err := gob.NewDecoder(&buf).Decode(dst); err != nil {
+			t.Fatal(err)
+		}
+		return dst
+	}},
+	{"binary", func(t *testing.T, src *Set[string], opts []Option) *Set[string] {
+		data, err := src.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := NewSet[string](opts...)
+		if err := dst.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		return dst
+	}},
+	{"json", func(t *testing.T, src *Set[string], opts []Option) *Set[string] {
+		var buf bytes.Buffer
+		if err := src.EncodeTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		dst := NewSet[string](opts...)
+		if err := dst.DecodeFrom(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return dst
+	}},
+	{"sql", func(t *testing.T, src *Set[string], opts []Option) *Set[string] {
+		value, err := src.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := NewSet[string](opts...)
+		if err := dst.Scan(value); err != nil {
+			t.Fatal(err)
+		}
+		return dst
+	}},
+	{"file", func(t *testing.T, src *Set[string], opts []Option) *Set[string] {
+		path := filepath.Join(t.TempDir(), "set.snapshot")
+		if err := src.SaveFile(path); err != nil {
+			t.Fatal(err)
+		}
+		dst := NewSet[string](opts...)
+		if err := dst.LoadFile(path); err != nil {
+			t.Fatal(err)
+		}
+		return dst
+	}},
+	{"recover", func(t *testing.T, src *Set[string], opts []Option) *Set[string] {
+		dir := t.TempDir()
+		if err := src.SaveFile(filepath.Join(dir, "set.snapshot")); err != nil {
+			t.Fatal(err)
+		}
+		dst := NewSet[string](opts...)
+		if err := dst.Recover(filepath.Join(dir, "set.snapshot"), filepath.Join(dir, "set.aof")); err != nil {
+			t.Fatal(err)
+		}
+		return dst
+	}},
+	{"import", func(t *testing.T, src *Set[string], opts []Option) *Set[string] {
+		dst := NewSet[string](opts...)
+		dst.Import(src.Export(), KeepLeftTTL)
+		return dst
+	}},
+	{"entries", func(t *testing.T, src *Set[string], opts []Option) *Set[string] {
+		return FromEntries(src.Entries(), opts...)
+	}},
+}
+
+
+func TestRestoreDefaultTTL(t *testing.T) {
+	for _, r := range restorers {
+		t.Run(r.name, func(t *testing.T) {
+			src := NewSet[string](WithDefaultTTL(time.Hour))
+			src.Add("persisted")
+			src.Persist("persisted")
+			src.AddWithExpire("short", time.Minute)
+
+			dst := r.restore(t, src, []Option{WithDefaultTTL(time.Hour)})
+			checkRestoredTTL(t, dst)
+		})
+	}
+}
+
+
+func TestRestoreBounded(t *testing.T) {
+	bounds := []struct {
+		name string
+		opts []Option
+		max  int
+	}{
+		{"max size", []Option{WithMaxSize(2)}, 2},
+		{"max weight", []Option{WithMaxWeight(2)}, 2},
+	}
+
+	for _, r := range restorers {
+		for _, b := range bounds {
+			t.Run(r.name + "/" + b.name, func(t *testing.T) {
+				src := NewSet[string]()
+				src.AddAll("a", "b", "c", "d")
+
+				dst := r.restore(t, src, b.opts)
+				if dst.Len() != b.max {
+					t.Fatalf("restored %d elements, want %d", dst.Len(), b.max)
+				}
+				dst.Add("e")
+				if dst.Len() != b.max || !dst.Contains("e") {
+					t.Fatalf("has %v after adding e, want %d elements with e", dst.GetAll(), b.max)
+				}
+			})
+		}
+	}
+}
+
+
+// Checks the elements added by TestCloneKeepsTTL and TestRestoreDefaultTTL.
+func checkRestoredTTL(t *testing.T, es *Set[string]) {
+	t.Helper()
+	if !es.Contains("persisted") {
+		t.Fatal("persisted element is missing")
+	}
+	if ttl, ok := es.TTL("persisted"); ok {
+		t.Fatalf("persisted element got ttl %v", ttl)
+	}
+	if ttl, ok := es.TTL("short"); !ok || ttl > time.Minute {
+		t.Fatalf("short element has ttl %v, want at most a minute", ttl)
+	}
+}
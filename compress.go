@@ -0,0 +1,66 @@
+package eset
+
+import (
+	"bytes"
+	"io"
+)
+
+// Writes the data to w compressed by the compress func of the set,
+// or as it is if the set has none.
+func(es *Set[T]) writeCompressed(w io.Writer, data []byte) error {
+	if es.compress == nil {
+		_, err := w.Write(data)
+		return err
+	}
+
+	cw := es.compress(w)
+	if _, err := cw.Write(data); err != nil {
+		cw.Close()
+		return err
+	}
+
+	return cw.Close()
+}
+
+
+// Returns all the data read from r decompressed by the decompress func of the set.
+func(es *Set[T]) readCompressed(r io.Reader) ([]byte, error) {
+	dr, err := es.decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := dr.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	return io.ReadAll(dr)
+}
+
+
+// segmentWriter compresses each write as a segment of its own,
+// which is what the append-only log needs
+// since it's written a batch of records at a time.
+// The segment is written to w with one write,
+// so that sealWriter encrypts it as a whole unless it's larger than a frame.
+type segmentWriter struct {
+	w        io.Writer
+	compress func(w io.Writer) io.WriteCloser
+}
+
+
+func(s segmentWriter) Write(p []byte) (int, error) {
+	var buf bytes.Buffer
+	cw := s.compress(&buf)
+	if _, err := cw.Write(p); err != nil {
+		cw.Close()
+		return 0, err
+	}
+	if err := cw.Close(); err != nil {
+		return 0, err
+	}
+	if _, err := s.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
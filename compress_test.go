@@ -0,0 +1,75 @@
+package eset
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func withGzip() Option {
+	return WithCompression(
+		func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	)
+}
+
+
+func TestCompression(t *testing.T) {
+	dir := t.TempDir()
+	plainPath, compressedPath := filepath.Join(dir, "plain"), filepath.Join(dir, "compressed")
+	plain := NewSet[string]()
+	compressed := NewSet[string](withGzip())
+	for i := 0; i < 1000; i++ {
+		plain.Add(fmt.Sprintf("element-%032d", i))
+		compressed.Add(fmt.Sprintf("element-%032d", i))
+	}
+	if err := plain.SaveFile(plainPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.SaveFile(compressedPath); err != nil {
+		t.Fatal(err)
+	}
+	if fileSize(t, compressedPath) >= fileSize(t, plainPath) / 2 {
+		t.Fatalf("compressed snapshot has %d bytes, the plain one %d", fileSize(t, compressedPath), fileSize(t, plainPath))
+	}
+
+	loaded := NewSet[string](withGzip())
+	if err := loaded.LoadFile(compressedPath); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Equal(compressed) {
+		t.Fatal("loaded set differs from the saved one")
+	}
+	if err := NewSet[string]().LoadFile(compressedPath); !errors.Is(err, ErrCompressed) {
+		t.Fatalf("error = %v, want ErrCompressed", err)
+	}
+	// the plain snapshots are still read
+	if err := loaded.LoadFile(plainPath); err != nil || !loaded.Equal(plain) {
+		t.Fatalf("can't load a plain snapshot: %v", err)
+	}
+}
+
+
+func TestCompressedAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.aof")
+	es := NewSet[string](withGzip())
+	if err := es.EnableAOF(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	es.AddAll("a", "b", "c")
+	es.Remove("b")
+	if err := es.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := NewSet[string](withGzip())
+	if err := replayed.ReplayAOF(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := sorted(replayed.GetAll()); !equalStrings(got, []string{"a", "c"}) {
+		t.Fatalf("replayed %v, want [a c]", got)
+	}
+}
@@ -0,0 +1,57 @@
+package eset
+
+import (
+	"maps"
+	"unsafe"
+)
+
+// Publishes a copy of the map for the readers of a copy-on-write set,
+// it must be called while holding the write lock.
+func(es *Set[T]) publish() {
+	elems := maps.Clone(es.elems)
+	es.snapshot.Store(&elems)
+	es.stale = false
+}
+
+
+// Returns the map for reading and the func to call when done with it.
+// It's the published copy if there is one,
+// otherwise the read lock is held until done.
+func(es *Set[T]) view() (elems map[T]base, done func()) {
+	if published := es.snapshot.Load(); published != nil {
+		return *published, func() {}
+	}
+
+	es.rlock()
+	return es.elems, es.runlock
+}
+
+
+// Returns the maps of both sets for reading, see view.
+// The locks are taken in the order of the addresses of the sets,
+// so that goroutines working on the same sets in the opposite order,
+// like a.Equal(b) and b.Equal(a), can't deadlock.
+func viewBoth[T comparable](one, other *Set[T]) (elems, otherElems map[T]base, done func()) {
+	if one == other {
+		elems, done = one.view()
+		return elems, elems, done
+	}
+
+	first, second := one, other
+	if uintptr(unsafe.Pointer(other)) < uintptr(unsafe.Pointer(one)) {
+		first, second = other, one
+	}
+
+	firstElems, firstDone := first.view()
+	secondElems, secondDone := second.view()
+	done = func() {
+		secondDone()
+		firstDone()
+	}
+
+	if first == one {
+		return firstElems, secondElems, done
+	}
+
+	return secondElems, firstElems, done
+}
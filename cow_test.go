@@ -0,0 +1,95 @@
+package eset
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadMostly(t *testing.T) {
+	es := NewSet[int](WithReadMostly())
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				es.Add(i)
+				es.Contains(i - 1)
+				es.Peek(i)
+				es.TTL(i)
+				if i % 3 == 0 {
+					es.Remove(i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if es.Contains(3) || !es.Contains(4) || es.Size() != 666 {
+		t.Fatalf("size = %d, want 666", es.Size())
+	}
+
+	es.AddWithExpire(-1, time.Millisecond)
+	if !es.Contains(-1) {
+		t.Fatal("lookup doesn't see the added element")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if es.Contains(-1) {
+		t.Fatal("lookup sees the expired element")
+	}
+
+	es.Clear()
+	if es.Contains(4) {
+		t.Fatal("lookup sees the cleared element")
+	}
+}
+
+
+func TestCopyOnWrite(t *testing.T) {
+	es := FromSlice([]int{1, 2, 3}, 0, WithCopyOnWrite())
+	if !es.Contains(1) || len(es.GetAll()) != 3 {
+		t.Fatalf("readers see %v, want [1 2 3]", es.GetAll())
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 300; i++ {
+				es.Add(i)
+				es.Contains(i)
+				es.GetAll()
+				es.ForEach(func(int) {})
+				if i % 2 == 0 {
+					es.Remove(i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if es.Contains(2) || !es.Contains(3) || es.Size() != 150 {
+		t.Fatalf("size = %d, want the 150 odd numbers", es.Size())
+	}
+
+	other := FromSlice([]int{3, 5, 1000}, 0, WithCopyOnWrite())
+	if len(es.Intersect(other).GetAll()) != 2 || other.IsSubSet(es) || es.Equal(other) {
+		t.Fatal("wrong set operations")
+	}
+
+	// the readers don't hold the lock, so they can write
+	es.ForEach(func(elem int) {
+		es.Remove(elem)
+	})
+	if es.Size() != 0 {
+		t.Fatalf("size = %d after removing everything", es.Size())
+	}
+
+	es.AddWithExpire(7, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if es.Contains(7) || len(es.GetAll()) != 0 {
+		t.Fatal("readers see the expired element")
+	}
+}
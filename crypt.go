@@ -0,0 +1,251 @@
+package eset
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Encrypts the snapshot files and the append-only log with AES-GCM,
+// the key must be 16, 24 or 32 bytes to select AES-128, AES-192 or AES-256.
+// The data is compressed before it's encrypted if the set has WithCompression.
+// Each snapshot, and each segment of the log written when the lock is released,
+// is sealed with a random nonce,
+// so tampering with them fails LoadFile and ReplayAOF,
+// and so does dropping or reordering the segments.
+// The log must be written with the same key it's replayed with.
+// It panics if the key has another length.
+func WithSnapshotKey(key []byte) Option {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic("eset: " + err.Error())
+	}
+	// never fails with the standard nonce size of AES
+	aead, _ := cipher.NewGCM(block)
+
+	return func(c *config) {
+		c.aead = aead
+	}
+}
+
+
+// Returns the data sealed by the aead, prefixed by a random nonce.
+// additionalData is authenticated but not encrypted.
+func seal(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize() + len(data) + aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, data, additionalData), nil
+}
+
+
+// Returns the data of sealed written by seal.
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, additionalData)
+}
+
+
+// the most data a frame of sealWriter seals, larger writes are split,
+// so that openReader refuses a frame claiming more before it's allocated
+const maxFrameSize = 1 << 20
+
+// the bit of the length of a frame that marks the final one
+const finalFrame = 1 << 31
+
+var errFrame = errors.New("eset: malformed frame in the encrypted data")
+
+// sealWriter seals the data written to it as frames,
+// each is the 4 bytes big endian length of the sealed data followed by it.
+// The index of a frame is authenticated with it,
+// so that dropping or reordering the frames fails openReader,
+// and so is whether it's the final one, which is empty and written by finish,
+// so that cutting off the frames at the end fails it as well.
+type sealWriter struct {
+	w    io.Writer
+	aead cipher.AEAD
+	// the index of the next frame
+	index uint64
+}
+
+
+func(s *sealWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), maxFrameSize)]
+		if err := s.writeFrame(chunk, false); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+
+	return written, nil
+}
+
+
+// Writes the final frame, nothing can be written after it.
+func(s *sealWriter) finish() error {
+	return s.writeFrame(nil, true)
+}
+
+
+func(s *sealWriter) writeFrame(p []byte, final bool) error {
+	sealed, err := seal(s.aead, p, frameData(s.index, final))
+	if err != nil {
+		return err
+	}
+
+	size := uint32(len(sealed))
+	if final {
+		size |= finalFrame
+	}
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4 + len(sealed)), size)
+	if _, err := s.w.Write(append(frame, sealed...)); err != nil {
+		return err
+	}
+
+	s.index++
+	return nil
+}
+
+
+// Returns the additional data a frame is sealed with,
+// which is its index and whether it's the final one.
+func frameData(index uint64, final bool) []byte {
+	data := binary.BigEndian.AppendUint64(make([]byte, 0, 9), index)
+	if final {
+		return append(data, 1)
+	}
+
+	return append(data, 0)
+}
+
+
+// openReader reads the frames written by sealWriter
+// and returns the data they seal.
+// It fails with io.ErrUnexpectedEOF if the frames end before the final one,
+// unless it's unfinished.
+type openReader struct {
+	r    io.Reader
+	aead cipher.AEAD
+	// the data of the current frame not read yet
+	buf  []byte
+	// the index of the next frame
+	index uint64
+	// whether the final frame is read
+	done bool
+	// whether the frames may end without the final one,
+	// e.g. the ones of a log still written to
+	unfinished bool
+}
+
+
+func(o *openReader) Read(p []byte) (int, error) {
+	for len(o.buf) == 0 {
+		data, err := o.next()
+		if err != nil {
+			return 0, err
+		}
+		o.buf = data
+	}
+
+	n := copy(p, o.buf)
+	o.buf = o.buf[n:]
+	return n, nil
+}
+
+
+// Returns the data of the next frame,
+// or io.EOF once the frames end.
+func(o *openReader) next() ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(o.r, size[:]); err == io.EOF && (o.done || o.unfinished) {
+		return nil, io.EOF
+	} else if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	if o.done {
+		// data after the final frame
+		return nil, errFrame
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	final := n & finalFrame != 0
+	n &^= finalFrame
+	if n > uint32(maxFrameSize + o.aead.NonceSize() + o.aead.Overhead()) {
+		return nil, errFrame
+	}
+
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(o.r, sealed); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	data, err := open(o.aead, sealed, frameData(o.index, final))
+	if err != nil {
+		return nil, err
+	}
+	o.index++
+	o.done = final
+	return data, nil
+}
+
+
+// Seals the frames read by r again with the indexes of w,
+// one frame at a time so that they keep their sizes.
+func reseal(w *sealWriter, r *openReader) error {
+	for {
+		data, err := r.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := w.writeFrame(data, r.done); err != nil {
+			return err
+		}
+	}
+}
+
+
+// Returns how many frames written by sealWriter r has
+// from where it's at to offset, r is left at offset.
+func countFrames(r io.ReadSeeker, offset int64) (uint64, error) {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	for pos < offset {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return 0, err
+		}
+		if pos, err = r.Seek(int64(binary.BigEndian.Uint32(size[:]) &^ finalFrame), io.SeekCurrent); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if pos != offset {
+		return 0, errFrame
+	}
+
+	return n, nil
+}
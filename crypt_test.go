@@ -0,0 +1,205 @@
+package eset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotKey(t *testing.T) {
+	key := make([]byte, 32)
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"encrypted", []Option{WithSnapshotKey(key)}},
+		{"compressed and encrypted", []Option{WithSnapshotKey(key), withGzip()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "set.snapshot")
+			es := NewSet[string](tt.opts...)
+			es.Add("secret")
+			if err := es.SaveFile(path); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("secret")) {
+				t.Fatal("snapshot has the element in plaintext")
+			}
+			if err := NewSet[string]().LoadFile(path); !errors.Is(err, ErrEncrypted) {
+				t.Fatalf("error = %v, want ErrEncrypted", err)
+			}
+
+			loaded := NewSet[string](tt.opts...)
+			if err := loaded.LoadFile(path); err != nil || !loaded.Contains("secret") {
+				t.Fatalf("loaded %v, %v", loaded.GetAll(), err)
+			}
+
+			data[len(data) - 1] ^= 1
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := loaded.LoadFile(path); err == nil {
+				t.Fatal("tampered snapshot is loaded")
+			}
+			if err := NewSet[string](WithSnapshotKey(make([]byte, 16))).LoadFile(path); err == nil {
+				t.Fatal("snapshot is loaded with another key")
+			}
+		})
+	}
+}
+
+
+func TestEncryptedAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.aof")
+	es := NewSet[string](WithSnapshotKey(make([]byte, 32)))
+	if err := es.EnableAOF(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	es.Add("secret")
+	es.Add("a")
+	es.Remove("secret")
+	if err := es.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatal("log has the element in plaintext")
+	}
+
+	replayed := NewSet[string](WithSnapshotKey(make([]byte, 32)))
+	if err := replayed.ReplayAOF(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed.GetAll(); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("replayed %v, want [a]", got)
+	}
+}
+
+
+func TestEncryptedAOFFrames(t *testing.T) {
+	key := make([]byte, 32)
+	path := filepath.Join(t.TempDir(), "set.aof")
+	es := NewSet[string](WithSnapshotKey(key))
+	if err := es.EnableAOF(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	// a frame for each release of the lock
+	es.Add("a")
+	es.Add("b")
+	es.Add("c")
+	if err := es.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, frames := data[:len(aofHeader)], [][]byte{}
+	for rest := data[len(aofHeader):]; len(rest) > 0; {
+		n := 4 + int(binary.BigEndian.Uint32(rest) &^ finalFrame)
+		frames, rest = append(frames, rest[:n]), rest[n:]
+	}
+	// the rewrite, a, b, c and the final one
+	if len(frames) != 5 {
+		t.Fatalf("log has %d frames, want 5", len(frames))
+	}
+	log := func(frames ...[]byte) []byte {
+		return bytes.Join(append([][]byte{header}, frames...), nil)
+	}
+	huge := binary.BigEndian.AppendUint32(nil, 1 << 30)
+
+	tests := []struct {
+		name string
+		data []byte
+		// nil if the replay fails with an error other than io.ErrUnexpectedEOF
+		want []string
+		err  error
+	}{
+		{"intact", log(frames...), []string{"a", "b", "c"}, nil},
+		{"reordered", log(frames[0], frames[2], frames[1], frames[3], frames[4]), nil, nil},
+		{"dropped", log(frames[0], frames[1], frames[3], frames[4]), nil, nil},
+		{"final dropped", log(frames[:4]...), []string{"a", "b", "c"}, io.ErrUnexpectedEOF},
+		{"cut", log(frames[:3]...), []string{"a", "b"}, io.ErrUnexpectedEOF},
+		{"trailing", log(append(frames, frames[4])...), nil, nil},
+		{"huge frame", log(frames[0], huge), nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			replayed := NewSet[string](WithSnapshotKey(key))
+			err := replayed.ReplayAOF(path)
+			if tt.want == nil {
+				if err == nil || errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Fatalf("error = %v, want the log to fail", err)
+				}
+				return
+			}
+			if err != tt.err {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if got := sorted(replayed.GetAll()); !equalStrings(got, tt.want) {
+				t.Fatalf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+
+func TestEncryptedAOFTrim(t *testing.T) {
+	key := make([]byte, 32)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "set.aof")
+	es := NewSet[string](WithSnapshotKey(key), WithAutoSnapshot(filepath.Join(dir, "set.snapshot"), time.Hour))
+	if err := es.EnableAOF(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	es.Add("a")
+	es.autoSnapshot()
+	es.Add("b")
+	// drops the frames logged before the first snapshot,
+	// the frames kept are sealed again with their new indexes
+	es.autoSnapshot()
+	es.Add("c")
+
+	// the log isn't closed yet
+	replayed := NewSet[string](WithSnapshotKey(key))
+	if err := replayed.ReplayAOF(path); err != io.ErrUnexpectedEOF {
+		t.Fatalf("error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if got := sorted(replayed.GetAll()); !equalStrings(got, []string{"b", "c"}) {
+		t.Fatalf("replayed %v, want [b c]", got)
+	}
+
+	// Close takes the last snapshot, which drops b as well
+	if err := es.Close(); err != nil {
+		t.Fatal(err)
+	}
+	replayed = NewSet[string](WithSnapshotKey(key))
+	if err := replayed.ReplayAOF(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed.GetAll(); !equalStrings(got, []string{"c"}) {
+		t.Fatalf("replayed %v, want [c]", got)
+	}
+}
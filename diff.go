@@ -0,0 +1,150 @@
+package eset
+
+// changeLog keeps the sequence of the last change of each element,
+// so that DiffSince can tell what has changed since a sequence.
+type changeLog[T comparable] struct {
+	// the elements in the set
+	changed map[T]uint64
+	// the removed elements by their keys
+	removed map[T]tombstone[T]
+	// the removals in the order they're made,
+	// some of them are stale if the element is added back
+	order      []removal[T]
+	maxRemoved int
+	// the sequence of the latest removal dropped to keep maxRemoved
+	floor uint64
+}
+
+type tombstone[T comparable] struct {
+	elem T
+	seq  uint64
+}
+
+type removal[T comparable] struct {
+	key T
+	seq uint64
+}
+
+
+// Keeps the sequence of the last change of each element for DiffSince,
+// and the last maxRemoved elements removed from the set,
+// or all of them if maxRemoved is less than or equal to 0.
+// Each removal kept costs the memory of its element.
+func WithChangeLog(maxRemoved int) Option {
+	return func(c *config) {
+		c.changeLog = true
+		c.maxRemoved = maxRemoved
+	}
+}
+
+
+func newChangeLog[T comparable](maxRemoved int) *changeLog[T] {
+	return &changeLog[T]{
+		changed:    make(map[T]uint64),
+		removed:    make(map[T]tombstone[T]),
+		maxRemoved: maxRemoved,
+	}
+}
+
+
+func(cl *changeLog[T]) put(key T, seq uint64) {
+	cl.changed[key] = seq
+	delete(cl.removed, key)
+}
+
+
+// Records the removal of an element, unless it isn't in the set.
+func(cl *changeLog[T]) remove(key, elem T, seq uint64) {
+	if _, ok := cl.changed[key]; !ok {
+		return
+	}
+
+	delete(cl.changed, key)
+	cl.removed[key] = tombstone[T]{elem: elem, seq: seq}
+	if cl.maxRemoved <= 0 {
+		return
+	}
+
+	cl.order = append(cl.order, removal[T]{key: key, seq: seq})
+	for len(cl.removed) > cl.maxRemoved {
+		oldest := cl.order[0]
+		cl.order = cl.order[1:]
+		if t, ok := cl.removed[oldest.key]; ok && t.seq == oldest.seq {
+			delete(cl.removed, oldest.key)
+			cl.floor = oldest.seq
+		}
+	}
+
+	if len(cl.order) > 2 * cl.maxRemoved {
+		// drop the stale removals
+		order := make([]removal[T], 0, len(cl.removed))
+		for _, r := range cl.order {
+			if t, ok := cl.removed[r.key]; ok && t.seq == r.seq {
+				order = append(order, r)
+			}
+		}
+		cl.order = order
+	}
+}
+
+
+// Returns the changes of the set since seq,
+// so that a replica can pull them instead of a full snapshot.
+// seq is the sequence of the last change, which is passed to the next call,
+// the first call passes 0.
+// added has the elements added or renewed since with the time they expire at,
+// and removed has the ones removed since.
+// The elements changed since that have expired by now are in removed,
+// since the replica hasn't seen their time to expire yet,
+// while the ones the replica already has expire there at the same time,
+// so they aren't in either.
+// The set must be created with WithChangeLog,
+// otherwise added has all the elements and removed is empty.
+// If seq is older than DiffFloor, some removals are no longer kept,
+// so added has all the elements, and the replica must drop the ones not in it.
+func(es *Set[T]) DiffSince(since uint64) (added, removed []Entry[T], seq uint64) {
+	es.rlock()
+	defer es.runlock()
+
+	cl := es.changes
+	if cl == nil || since < cl.floor {
+		return es.entriesOf(es.elems), nil, es.seq
+	}
+
+	now := es.nowNano()
+	for key, changedAt := range cl.changed {
+		if changedAt <= since {
+			continue
+		}
+
+		b := es.elems[key]
+		if b.isExpired(now) {
+			removed = append(removed, Entry[T]{Elem: es.elemOf(key, b)})
+		} else {
+			added = append(added, Entry[T]{Elem: es.elemOf(key, b), ExpireAt: b.expireTime()})
+		}
+	}
+
+	for _, t := range cl.removed {
+		if t.seq > since {
+			removed = append(removed, Entry[T]{Elem: t.elem})
+		}
+	}
+
+	return added, removed, es.seq
+}
+
+
+// Returns the sequence of the latest removal
+// no longer kept by the set because of the maxRemoved of WithChangeLog,
+// the replicas whose sequence is older have to sync all the elements.
+func(es *Set[T]) DiffFloor() uint64 {
+	es.rlock()
+	defer es.runlock()
+
+	if es.changes == nil {
+		return 0
+	}
+
+	return es.changes.floor
+}
@@ -0,0 +1,111 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func elemsOf(entries []Entry[string]) []string {
+	elems := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		elems = append(elems, entry.Elem)
+	}
+
+	return sorted(elems)
+}
+
+
+func elemsOfInts(entries []Entry[int]) []int {
+	elems := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		elems = append(elems, entry.Elem)
+	}
+
+	return elems
+}
+
+
+func TestDiffSince(t *testing.T) {
+	es := NewSet[string](WithChangeLog(0))
+	es.Add("a")
+	es.Add("b")
+	added, removed, seq := es.DiffSince(0)
+	if !equalStrings(elemsOf(added), []string{"a", "b"}) || len(removed) != 0 {
+		t.Fatalf("added %v, removed %v, want [a b] and nothing", elemsOf(added), elemsOf(removed))
+	}
+
+	es.Remove("a")
+	es.Add("c")
+	es.Remove("missing")
+	added, removed, seq = es.DiffSince(seq)
+	if !equalStrings(elemsOf(added), []string{"c"}) || !equalStrings(elemsOf(removed), []string{"a"}) {
+		t.Fatalf("added %v, removed %v, want [c] and [a]", elemsOf(added), elemsOf(removed))
+	}
+
+	es.Clear()
+	added, removed, _ = es.DiffSince(seq)
+	if len(added) != 0 || !equalStrings(elemsOf(removed), []string{"b", "c"}) {
+		t.Fatalf("added %v, removed %v after Clear, want nothing and [b c]", elemsOf(added), elemsOf(removed))
+	}
+
+	if _, _, last := es.DiffSince(0); last == 0 {
+		t.Fatal("sequence doesn't move")
+	}
+}
+
+
+func TestDiffSinceExpired(t *testing.T) {
+	es := NewSet[string](WithChangeLog(0))
+	es.AddWithExpire("a", 20 * time.Millisecond)
+	_, _, seq := es.DiffSince(0)
+
+	// b expires before the replica sees it, a is already known by the replica
+	es.AddWithExpire("b", time.Nanosecond)
+	time.Sleep(30 * time.Millisecond)
+	added, removed, _ := es.DiffSince(seq)
+	if len(added) != 0 || !equalStrings(elemsOf(removed), []string{"b"}) {
+		t.Fatalf("added %v, removed %v, want nothing and [b]", elemsOf(added), elemsOf(removed))
+	}
+}
+
+
+func TestDiffFloor(t *testing.T) {
+	es := NewSet[int](WithChangeLog(2))
+	for i := 0; i < 5; i++ {
+		es.Add(i)
+	}
+	_, _, seq := es.DiffSince(0)
+	es.Remove(0)
+	es.Remove(1)
+	es.Remove(2)
+
+	// the removal of 0 is dropped, so the replica syncs all the elements
+	if es.DiffFloor() <= seq {
+		t.Fatalf("floor = %d, want after %d", es.DiffFloor(), seq)
+	}
+	added, removed, _ := es.DiffSince(seq)
+	if got := sortedInts(elemsOfInts(added)); !equalInts(got, []int{3, 4}) || removed != nil {
+		t.Fatalf("added %v, removed %v, want [3 4] and nothing", got, removed)
+	}
+
+	for i := 0; i < 100; i++ {
+		es.Add(9)
+		es.Remove(9)
+	}
+	if len(es.changes.order) > 4 {
+		t.Fatalf("keeps %d removals, want at most 4", len(es.changes.order))
+	}
+	if es.Union(es).changes != nil {
+		t.Fatal("derived set inherits the change log")
+	}
+}
+
+
+func TestDiffSinceWithoutChangeLog(t *testing.T) {
+	es := setOf("a", "b")
+	es.Remove("a")
+	added, removed, _ := es.DiffSince(0)
+	if !equalStrings(elemsOf(added), []string{"b"}) || removed != nil {
+		t.Fatalf("added %v, removed %v, want [b] and nothing", elemsOf(added), elemsOf(removed))
+	}
+}
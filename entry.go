@@ -0,0 +1,101 @@
+package eset
+
+import (
+	"reflect"
+	"time"
+)
+
+// Entry is an element with the time it expires at,
+// ExpireAt is zero if the element doesn't have ttl.
+type Entry[T comparable] struct {
+	Elem     T
+	ExpireAt time.Time
+}
+
+
+// Returns the unexpired elements with the time they expire at,
+// e.g. to encode the set in a format of choice.
+func(es *Set[T]) Entries() []Entry[T] {
+	elems, done := es.view()
+	defer done()
+	return es.entriesOf(elems)
+}
+
+
+// Returns the unexpired entries of the elements,
+// the lock must be held unless the map is a published copy.
+func(es *Set[T]) entriesOf(elems map[T]base) []Entry[T] {
+	now := es.nowNano()
+	entries := make([]Entry[T], 0, len(elems))
+	for key, base := range elems {
+		if !base.isExpired(now) {
+			entries = append(entries, Entry[T]{Elem: es.elemOf(key, base), ExpireAt: base.expireTime()})
+		}
+	}
+
+	return entries
+}
+
+
+// Same as Entries, which is the counterpart of Import,
+// e.g. to migrate the elements to another set without losing their ttl.
+func(es *Set[T]) Export() []Entry[T] {
+	return es.Entries()
+}
+
+
+// Adds the entries to the set, e.g. the ones exported by another set,
+// each of them expires at its ExpireAt, or never if it's zero.
+// The entries that have expired are skipped.
+// The expiration time of an element that is already in the set
+// is decided by policy, the entry is the right one.
+func(es *Set[T]) Import(entries []Entry[T], policy TTLMergePolicy) {
+	es.lock()
+	defer es.unlock()
+
+	now := es.now()
+	for _, entry := range entries {
+		if !entry.ExpireAt.IsZero() && !entry.ExpireAt.After(now) {
+			continue
+		}
+
+		b := es.entryBase(entry, now)
+		if old, isExist := es.elems[es.keyOf(entry.Elem)]; isExist && !es.isExpired(old) {
+			if b = mergeBaseWith(policy, old, b); b == old {
+				continue
+			}
+		}
+		es.add(entry.Elem, b)
+	}
+}
+
+
+// Returns a set that has the elements of the entries,
+// each of them expires at its ExpireAt, or never if it's zero,
+// which is the counterpart of Entries.
+func FromEntries[T comparable](entries []Entry[T], opts ...Option) *Set[T] {
+	es := newSet[T](len(entries), opts)
+	es.putEntries(entries)
+	es.start()
+	return es
+}
+
+
+// Replaces the elements of the set with the entries,
+// the options of the set are kept.
+func(es *Set[T]) replaceEntries(entries []Entry[T]) {
+	es.lock()
+	es.gen++
+	es.init()
+	es.putEntries(entries)
+	es.unlock()
+}
+
+
+// Returns whether the element can be a key of the map,
+// a decoded element of an ExpirableSet may be a slice or a map,
+// which panics once it's hashed.
+func(es *Set[T]) hashable(elem T) bool {
+	key := es.keyOf(elem)
+	return reflect.ValueOf(&key).Elem().Comparable()
+}
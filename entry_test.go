@@ -0,0 +1,36 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImport(t *testing.T) {
+	src := NewSet[string]()
+	src.AddWithExpire("a", time.Hour)
+	src.Add("b")
+	src.AddWithExpire("c", time.Minute)
+
+	dst := NewSet[string]()
+	dst.AddWithExpire("c", time.Hour)
+	dst.Import(src.Export(), KeepLeftTTL)
+	if ttl, _ := dst.TTL("c"); ttl <= 59 * time.Minute {
+		t.Fatalf("ttl of c = %v, want the one of the set", ttl)
+	}
+	if ttl, ok := dst.TTL("a"); !ok || ttl <= 59 * time.Minute {
+		t.Fatalf("ttl of a = %v, %v, want about an hour", ttl, ok)
+	}
+	if _, ok := dst.TTL("b"); ok || !dst.Contains("b") {
+		t.Fatal("b isn't imported without a ttl")
+	}
+
+	dst.Import(src.Export(), KeepRightTTL)
+	if ttl, _ := dst.TTL("c"); ttl > time.Minute {
+		t.Fatalf("ttl of c = %v, want the one of the entry", ttl)
+	}
+
+	dst.Import([]Entry[string]{{Elem: "x", ExpireAt: time.Now().Add(-time.Second)}}, KeepMaxTTL)
+	if dst.Contains("x") {
+		t.Fatal("expired entry is imported")
+	}
+}
@@ -0,0 +1,67 @@
+package eset
+
+import "time"
+
+type equalConfig struct {
+	compareTTL bool
+	tolerance  time.Duration
+}
+
+type EqualOption func(*equalConfig)
+
+
+// Requires the elements to expire at the same time in both sets,
+// within the tolerance.
+// An element without ttl only matches an element without ttl.
+func WithTTLTolerance(tolerance time.Duration) EqualOption {
+	return func(c *equalConfig) {
+		c.compareTTL = true
+		c.tolerance = tolerance
+	}
+}
+
+
+// Same as Equal, but the expired elements of both sets are ignored,
+// and the expiration time can be compared with WithTTLTolerance.
+func(es *Set[T]) EqualOpts(other *Set[T], opts ...EqualOption) bool {
+	var c equalConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
+
+	now, otherNow := es.nowNano(), other.nowNano()
+	size := 0
+	for elem, base := range elems {
+		if base.isExpired(now) {
+			continue
+		}
+
+		size++
+		otherBase, isExist := otherElems[elem]
+		if !isExist || otherBase.isExpired(otherNow) {
+			return false
+		}
+		if c.compareTTL && !sameExpireTime(base, otherBase, c.tolerance) {
+			return false
+		}
+	}
+
+	return size == other.countUnexpired(otherElems)
+}
+
+
+func sameExpireTime(one, other base, tolerance time.Duration) bool {
+	if !one.hasTTL() || !other.hasTTL() {
+		return one.hasTTL() == other.hasTTL()
+	}
+
+	diff := one.expireAt - other.expireAt
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return diff <= int64(tolerance)
+}
@@ -0,0 +1,35 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEqualOpts(t *testing.T) {
+	one := setOf(1)
+	one.AddWithExpire(2, time.Hour)
+	one.AddWithExpire(7, time.Nanosecond)
+	other := setOf(1)
+	other.AddWithExpire(2, time.Hour + time.Second)
+	time.Sleep(time.Millisecond)
+
+	if !one.EqualOpts(other) || !other.EqualOpts(one) {
+		t.Fatal("sets differing in an expired element aren't equal")
+	}
+	if one.EqualOpts(other, WithTTLTolerance(time.Millisecond)) {
+		t.Fatal("expiration times a second apart are equal within a millisecond")
+	}
+	if !one.EqualOpts(other, WithTTLTolerance(2 * time.Second)) {
+		t.Fatal("expiration times a second apart aren't equal within 2 seconds")
+	}
+
+	persisted := setOf(1, 2)
+	if one.EqualOpts(persisted, WithTTLTolerance(time.Hour)) {
+		t.Fatal("element with ttl is equal to one without")
+	}
+
+	other.Add(3)
+	if one.EqualOpts(other) {
+		t.Fatal("sets of different elements are equal")
+	}
+}
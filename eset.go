@@ -2,143 +2,928 @@ package eset
 
 import (
 	"errors"
+	"maps"
 	"sync"
+	"sync/atomic"
 	"time"
-	"unsafe"
 )
 
+// Deprecated: it was the load factor of the maps before Go 1.24,
+// and it's no longer used.
 const FACTOR = 6.5
 
-type ExpirableSet struct {
-	elems    map[interface{}]*base
-	capacity int
+// how many expired elements are deleted at most
+// each time the lock is held by a cleanup
+const expireBatchSize = 1000
+
+var (
+	ErrElemNotExist = errors.New("elem doesn't exist")
+	ErrElemNoTTL    = errors.New("elem doesn't have ttl")
+	// returned by MarshalBinary and UnmarshalBinary
+	ErrUnsupportedType = errors.New("elem type is not supported by the binary format")
+	ErrBinaryVersion   = errors.New("unknown version of the binary format")
+	// returned by LoadFile
+	ErrSnapshotFormat = errors.New("not a snapshot file of eset")
+	ErrCompressed     = errors.New("snapshot is compressed, but the set has no WithCompression")
+	ErrEncrypted      = errors.New("snapshot is encrypted, but the set has no WithSnapshotKey")
+	// returned by the decoders when a decoded element can't be a key of the map,
+	// e.g. a slice decoded into an element of an ExpirableSet
+	ErrUnhashable = errors.New("elem is not hashable")
+)
+
+// Set is an expirable, goroutine safe set
+// whose elements are of type T.
+// The elements are kept in a Go map, which is
+// an open-addressing swiss table since Go 1.24,
+// so there is no option for another backend, use a typed set
+// instead of an ExpirableSet to keep the elements unboxed.
+// The zero value is an empty set without options ready to use,
+// so a Set can be embedded in a struct without calling a constructor.
+type Set[T comparable] struct {
+	elems    map[T]base
 	mutex    sync.RWMutex
+	config
+	// index of the elements with ttl
+	expires expirer[T]
+	evictor evictor[T]
+	// the weights of the elements if the set has a weigher
+	weights     map[T]int64
+	totalWeight int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	expiredCh      chan T
+	expiredDropped atomic.Uint64
+	quarantined    []Entry[T]
+	// the elements that left the set while holding the lock,
+	// their callbacks are called once it's released
+	pending []eviction[T]
+	// unix nano of when the expiration is paused, 0 if not paused
+	pausedAt atomic.Int64
+	// the elements not renewed by the refresh func, with the base they were declined at
+	declined map[T]base
+	// the most elements the map has held since it's allocated
+	peak int
+	// the keys changed while the map is being reallocated,
+	// nil if it's not
+	dirty map[T]struct{}
+	// the append-only log, nil unless EnableAOF is called
+	aof *aofLog
+	// the error of the last auto snapshot
+	snapshotErr atomic.Pointer[error]
+	// the store of WithStore, nil if it has none
+	store      Store[T]
+	storeErr   atomic.Pointer[error]
+	// the changes are not written to the store while it's set
+	unmirrored bool
+	// the queue of WithWriteBehind, nil if the changes are written through
+	writeBehind *writeBehind[T]
+	// whether the store is cleared once the lock is released, see sweepStore
+	storeCleared bool
+	// the sweeps of the store not done yet,
+	// and the elements written to the store since it's cleared, nil if there is none
+	sweeps    int
+	rewritten map[T]struct{}
+	// whether an element of the store belongs to the set,
+	// nil unless the set is a shard of a ShardedSet
+	owns func(elem T) bool
+	// bumped by every change
+	seq uint64
+	// the change log of WithChangeLog, nil if it has none
+	changes *changeLog[T]
+	// the counters of WithStats, nil if it has none
+	stats *statsCounters
+	// bumped whenever the map is replaced as a whole,
+	// so that the work done across releases of the lock can tell
+	gen uint64
+	// a copy of the map for the lookups without the lock,
+	// nil unless the set is read mostly
+	readMap *sync.Map
+	// the published copy of the map if the set is copy-on-write,
+	// nil until it's published
+	snapshot atomic.Pointer[map[T]base]
+	// whether the map has changed since it's published
+	stale bool
+	// unix nano of the cached time, 0 if the set has no coarse clock
+	clock atomic.Int64
+	// the number of elements in the map, for Len
+	count atomic.Int64
 }
 
+
+// ExpirableSet is a set that can hold elements of any comparable type.
+type ExpirableSet = Set[interface{}]
+
+// base is the metadata of an element, it's stored by value in the map,
+// so that adding an element with ttl doesn't allocate.
+// The zero base means no ttl.
 type base struct {
-	expireTime time.Time
+	// unix nano of when the element expires, 0 if it has no ttl
+	expireAt int64
+	// the ttl the element was given, used to renew it
+	ttl time.Duration
+	// nil unless the set has a max lifetime or a key func
+	ext *extra
 }
 
-// the underlying struct of map
-type hmap struct {
-	count      int   // live cells == size of ma
-	flags      uint8
-	B          uint8 // log_2 of buckets (can hold up to loadFactor * 2^B items)
+// extra is the metadata that only some sets need,
+// it's never modified once stored.
+// It's not reused through a pool either, since the expiration index
+// and the copies of the map may still refer to it after it's replaced.
+type extra struct {
+	// unix nano the element can't be renewed beyond, 0 if no max lifetime
+	deadline int64
+	// the original element, only kept when the set has a key func
+	elem interface{}
 }
 
 
-func New() *ExpirableSet {
-	es := &ExpirableSet{}
-	es.init()
+func New(opts ...Option) *ExpirableSet {
+	return NewSet[interface{}](opts...)
+}
+
+
+// Returns a typed set whose elements are of type T.
+func NewSet[T comparable](opts ...Option) *Set[T] {
+	es := newSet[T](0, opts)
+	es.start()
 	return es
 }
 
 
-// Assigns a initial capacity to the set
-// to reduce the performance consumption caused by expansion.
-// Because the implementation of eset is map,
-// its capacity expansion mechanism is the same as map,
-// that is, when (capacity / 2^hmap.B) > loadFactor,
-// the expansion will be triggered.
-func NewWithCapacity(capacity int) *ExpirableSet{
-	es := &ExpirableSet{}
-	if capacity <= 8 {
-		es.capacity = 8
-	} else {
-		// 13 is FACTOR * 2
-		es.capacity = FACTOR * 2 << (capacity / 13)
+// Returns a set that never locks, for the callers
+// that only use it from one goroutine and don't want to pay for the lock.
+// It's not safe for concurrent use, so it can't have a janitor nor an auto snapshot,
+// and the sets derived from it, like the results of set operations,
+// are unsafe as well.
+func NewUnsafe(opts ...Option) *ExpirableSet {
+	return NewUnsafeSet[interface{}](opts...)
+}
+
+
+// Returns a typed set that never locks, see NewUnsafe.
+func NewUnsafeSet[T comparable](opts ...Option) *Set[T] {
+	es := newSet[T](0, append(opts[:len(opts):len(opts)], withoutLock))
+	es.start()
+	return es
+}
+
+
+// Assigns a initial capacity to the set.
+//
+// Deprecated: use New with WithCapacity instead.
+func NewWithCapacity(capacity int, opts ...Option) *ExpirableSet{
+	return NewSetWithCapacity[interface{}](capacity, opts...)
+}
+
+
+// Returns a typed set with an initial capacity.
+//
+// Deprecated: use NewSet with WithCapacity instead.
+func NewSetWithCapacity[T comparable](capacity int, opts ...Option) *Set[T] {
+	return NewSet[T](append([]Option{WithCapacity(capacity)}, opts...)...)
+}
+
+
+// Returns a set that has all elements of the slice.
+// If ttl is greater than 0, the elements will expire after it.
+func FromSlice[T comparable](elems []T, ttl time.Duration, opts ...Option) *Set[T] {
+	es := newSet[T](len(elems), opts)
+	var b base
+	if ttl > 0 {
+		b = es.buildBase(ttl)
+	}
+
+	for _, elem := range elems {
+		es.add(elem, b)
+	}
+
+	es.start()
+	return es
+}
+
+
+// Returns a set that has all keys of the map,
+// each of them expires after the ttl it maps to.
+// Keys mapped to a ttl less than or equal to 0 never expire.
+func FromMap[T comparable](elems map[T]time.Duration, opts ...Option) *Set[T] {
+	es := newSet[T](len(elems), opts)
+	now := es.now()
+	for elem, ttl := range elems {
+		var b base
+		if ttl > 0 {
+			b = base{expireAt: now.Add(ttl).UnixNano(), ttl: ttl}
+		}
+		es.add(elem, b)
 	}
 
+	es.start()
+	return es
+}
+
+
+// Returns a set that has all elements received from the channel,
+// it returns after the channel is closed.
+func FromChannel[T comparable](ch <-chan T, opts ...Option) *Set[T] {
+	es := newSet[T](0, opts)
+	for elem := range ch {
+		es.add(elem, base{})
+	}
+
+	es.start()
+	return es
+}
+
+
+// Returns a set that is configured but not started yet,
+// so that it can be filled without locking.
+func newSet[T comparable](capacity int, opts []Option) *Set[T] {
+	es := &Set[T]{}
+	es.shrinkRatio = defaultShrinkRatio
+	es.apply(opts)
+	if capacity > es.capacity {
+		es.capacity = capacity
+	}
 	es.init()
+	if es.expiredBuffer > 0 {
+		es.expiredCh = make(chan T, es.expiredBuffer)
+	}
+
+	if es.changeLog {
+		es.changes = newChangeLog[T](es.maxRemoved)
+	}
+	if es.countStats {
+		es.stats = &statsCounters{}
+	}
+
+	if es.backend != nil {
+		store, ok := es.backend.(Store[T])
+		if !ok {
+			panic("eset: the store doesn't have the element type of the set")
+		}
+		es.store = store
+		if es.writeBehindSize > 0 {
+			es.writeBehind = newWriteBehind[T](es.writeBehindSize, es.writeBehindInterval)
+		}
+	}
+
+	if es.refreshFn != nil {
+		es.declined = make(map[T]base)
+		if es.janitorInterval == 0 {
+			es.janitorInterval = es.refreshWindow / 2
+		}
+	}
+
+	if es.unsync && es.janitorInterval > 0 {
+		panic("eset: an unsafe set can't have a janitor")
+	}
+	if es.unsync && es.snapshotInterval > 0 && es.snapshotSink != nil {
+		panic("eset: an unsafe set can't have an auto snapshot")
+	}
+
 	return es
 }
 
 
-func(es *ExpirableSet) init() {
+// Returns an empty set configured like the set,
+// for the results of set operations.
+// Background goroutines are not started for it.
+func(es *Set[T]) derive() *Set[T] {
+	return es.deriveSized(0)
+}
+
+
+// Same as derive, with the map sized for the capacity.
+func(es *Set[T]) deriveSized(capacity int) *Set[T] {
+	newEs := &Set[T]{config: es.config.inherited()}
+	newEs.capacity = capacity
+	newEs.init()
+	// the capacity is not kept for the later Clear
+	newEs.capacity = 0
+	return newEs
+}
+
+
+// Publishes the set and starts its background goroutines.
+func(es *Set[T]) start() {
+	if es.snapshotSink != nil {
+		es.restoreSnapshot()
+	}
+
+	if es.copyOnWrite {
+		es.publish()
+	}
+
+	if es.janitorInterval > 0 || es.clockPrecision > 0 || es.snapshotInterval > 0 && es.snapshotSink != nil || es.writeBehind != nil {
+		es.stop = make(chan struct{})
+	}
+
+	if es.clockPrecision > 0 {
+		es.startClock()
+	}
+
+	if es.janitorInterval > 0 {
+		es.startJanitor()
+	}
+
+	if es.snapshotInterval > 0 && es.snapshotSink != nil {
+		go es.runAutoSnapshot()
+	}
+
+	if es.writeBehind != nil {
+		go es.runWriteBehind(es.writeBehind)
+	}
+}
+
+
+func(es *Set[T]) apply(opts []Option) {
+	for _, opt := range opts {
+		opt(&es.config)
+	}
+
+	if es.keyFunc != nil {
+		if _, ok := interface{}((*T)(nil)).(*interface{}); !ok {
+			panic("eset: WithHasher and WithKeyFunc only work with sets of interface{}")
+		}
+	}
+
+	if es.maxWeight > 0 && es.weigher == nil {
+		es.weigher = func(interface{}) int64 {
+			return 1
+		}
+	}
+}
+
+
+func(es *Set[T]) init() {
+	if es.changes != nil && len(es.elems) > 0 {
+		es.seq++
+		for key, b := range es.elems {
+			es.changes.remove(key, es.elemOf(key, b), es.seq)
+		}
+	}
+
 	if es.capacity > 0 {
-		es.elems = make(map[interface{}]*base, es.capacity)
+		es.elems = make(map[T]base, es.capacity)
 	} else {
-		es.elems = make(map[interface{}]*base)
+		es.elems = make(map[T]base)
+	}
+	es.peak = es.capacity
+	es.dirty = nil
+	es.count.Store(0)
+	if es.readMostly {
+		if es.readMap == nil {
+			es.readMap = &sync.Map{}
+		} else {
+			es.readMap.Clear()
+		}
+	}
+	es.snapshot.Store(nil)
+	es.stale = true
+	es.expires = es.newExpirer()
+	es.evictor = es.newEvictor()
+	if es.weigher != nil {
+		es.weights = make(map[T]int64)
+		es.totalWeight = 0
+	}
+	if es.aof != nil {
+		var key T
+		es.logAOF(aofClear, key, base{})
+	}
+	if es.store != nil {
+		es.clearStore()
+	}
+}
+
+
+func(es *Set[T]) buildBase(ttl time.Duration) base {
+	return base{
+		expireAt: es.now().Add(ttl).UnixNano(),
+		ttl:      ttl,
+	}
+}
+
+
+// Returns the base of the elements added without ttl,
+// which has the default ttl if the set has one.
+func(es *Set[T]) defaultBase() base {
+	if es.defaultTTL > 0 {
+		return es.buildBase(es.defaultTTL)
+	}
+
+	return base{}
+}
+
+
+func(es *Set[T]) add(elem T, b base) {
+	if es.maxLifetime > 0 && b.deadline() == 0 {
+		b = es.limitLifetime(b)
+	}
+
+	if es.keyFunc != nil {
+		b.ext = &extra{deadline: b.deadline(), elem: elem}
+	}
+
+	if es.stats != nil {
+		es.stats.adds.Add(1)
+	}
+
+	key := es.keyOf(elem)
+	if es.onEvict != nil {
+		if old, isExist := es.elems[key]; isExist && !es.isExpired(old) {
+			es.pend(es.elemOf(key, old), Replaced)
+		}
+	}
+	es.put(key, b)
+}
+
+
+// Stores the base under the key,
+// and indexes it if it has ttl.
+func(es *Set[T]) put(key T, b base) {
+	if es.expires == nil {
+		// the zero value of Set is initialized by its first write
+		es.init()
 	}
+
+	_, isExist := es.elems[key]
+	es.elems[key] = b
+	es.changed(key, b, false)
+	if !isExist {
+		es.count.Add(1)
+	}
+	if len(es.elems) > es.peak {
+		es.peak = len(es.elems)
+	}
+	if b.hasTTL() {
+		es.expires.push(key, b)
+		if es.expires.len() > 2 * len(es.elems) + 64 {
+			es.rebuildExpires()
+		}
+	}
+
+	if es.weigher != nil {
+		es.weigh(key, b)
+	}
+
+	if es.evictor != nil {
+		es.evictor.add(key)
+		es.evict(key, !isExist)
+	}
+}
+
+
+func(es *Set[T]) del(key T) {
+	b, isExist := es.elems[key]
+	if isExist {
+		es.count.Add(-1)
+	}
+	delete(es.elems, key)
+	es.changed(key, b, true)
+	if es.weigher != nil {
+		es.totalWeight -= es.weights[key]
+		delete(es.weights, key)
+	}
+
+	if es.evictor != nil {
+		es.evictor.remove(key)
+	}
+}
+
+
+// Records that the key is stored or deleted
+// for the reallocation in progress, the copies of the map,
+// the append-only log and the store.
+func(es *Set[T]) changed(key T, b base, isDeleted bool) {
+	if es.dirty != nil {
+		es.dirty[key] = struct{}{}
+	}
+
+	if es.readMap != nil {
+		if isDeleted {
+			es.readMap.Delete(key)
+		} else {
+			es.readMap.Store(key, b)
+		}
+	}
+
+	if es.aof != nil {
+		if isDeleted {
+			es.logAOF(aofDel, key, b)
+		} else {
+			es.logAOF(aofAdd, key, b)
+		}
+	}
+
+	if es.store != nil {
+		es.mirror(key, b, isDeleted)
+	}
+
+	es.seq++
+	if es.changes != nil {
+		if isDeleted {
+			es.changes.remove(key, es.elemOf(key, b), es.seq)
+		} else {
+			es.changes.put(key, es.seq)
+		}
+	}
+
+	es.stale = true
+}
+
+
+// Replaces the base of an existed element with one expiring at expireTime,
+// or never expiring if expireTime is zero.
+func(es *Set[T]) setExpireTime(key T, b base, expireTime time.Time, ttl time.Duration) {
+	expireAt := unixNano(expireTime)
+	if deadline := b.deadline(); deadline != 0 && (expireAt == 0 || expireAt > deadline) {
+		expireAt = deadline
+	}
+
+	if expireAt == 0 {
+		ttl = 0
+	}
+
+	es.put(key, base{expireAt: expireAt, ttl: ttl, ext: b.ext})
 }
 
 
-func(es *ExpirableSet) buildBase(ttl time.Duration) *base {
-	return &base{
-		expireTime: time.Now().Add(ttl),
+// Returns a copy of the base of a new element
+// that can't live longer than the max lifetime.
+func(es *Set[T]) limitLifetime(b base) base {
+	deadline := es.now().Add(es.maxLifetime).UnixNano()
+	newBase := base{expireAt: deadline, ext: &extra{deadline: deadline}}
+	if b.hasTTL() && b.expireAt < deadline {
+		newBase.expireAt = b.expireAt
+		newBase.ttl = b.ttl
 	}
+
+	return newBase
 }
 
 
-func(es *ExpirableSet) add(elem interface{}, base *base) {
-	es.elems[elem] = base
+// Renews the ttl of an existed element.
+// Returns false if the element doesn't exist.
+func(es *Set[T]) touch(key T) bool {
+	es.lock()
+	defer es.unlock()
+	return es.renew(key)
 }
 
 
-func(es *ExpirableSet) contains(elem interface{}) bool {
+// Same as touch, but the write lock must be held.
+func(es *Set[T]) renew(key T) bool {
+	b, isExist := es.elems[key]
+	if !isExist || es.isExpired(b) {
+		return false
+	}
+
+	if es.evictor != nil {
+		es.evictor.access(key)
+	}
+
+	if b.ttl > 0 {
+		es.setExpireTime(key, b, es.now().Add(b.ttl), b.ttl)
+	}
+	return true
+}
+
+
+// Returns the key of the element in the map,
+// which is the element itself unless the set has a key func.
+// It's on the path of every lookup, so the element
+// must not be converted to interface{} unless a key func is set,
+// which keeps Add and Contains of typed sets free of allocations.
+func(es *Set[T]) keyOf(elem T) T {
+	if es.keyFunc == nil {
+		return elem
+	}
+
+	return interface{}(es.keyFunc(elem)).(T)
+}
+
+
+// Returns the element stored under the key.
+func(es *Set[T]) elemOf(key T, b base) T {
+	if es.keyFunc == nil {
+		return key
+	}
+
+	return b.ext.elem.(T)
+}
+
+
+func(es *Set[T]) contains(elem T) bool {
 	_, isExist := es.elems[elem]
 	return isExist
 }
 
 
-func(es *ExpirableSet) delExpiredElems() {
-	for elem, base := range es.elems {
-		if base.isExpired() {
-			delete(es.elems, elem)
+// Returns the base stored under the key for the readers,
+// it doesn't take the lock if the set is read mostly or copy-on-write.
+func(es *Set[T]) lookup(key T) (b base, isExist bool) {
+	if elems := es.snapshot.Load(); elems != nil {
+		b, isExist = (*elems)[key]
+		return b, isExist
+	}
+
+	if es.readMap != nil {
+		v, isExist := es.readMap.Load(key)
+		if isExist {
+			b = v.(base)
 		}
+		return b, isExist
+	}
+
+	es.rlock()
+	b, isExist = es.elems[key]
+	es.runlock()
+	return b, isExist
+}
+
+
+// Deletes at most limit expired elements, or all of them if limit is 0,
+// returns whether there are more to delete.
+func(es *Set[T]) delExpiredElems(limit int) (more bool) {
+	if es.expires == nil {
+		return false
 	}
+
+	return es.expires.popExpired(es.now(), limit, func(item expireItem[T]) {
+		if es.elems[item.key] == item.base {
+			es.expire(item.key, item.base)
+		}
+	})
 }
 
 
-func(es *ExpirableSet) largerThan(other *ExpirableSet) bool {
-	return len(es.elems) > len(other.elems)
+// Deletes the expired elements in batches,
+// the lock is released between the batches
+// so that a cleanup of a huge set doesn't block the others for long.
+func(es *Set[T]) cleanExpired() {
+	for more := true; more; {
+		es.lock()
+		start := time.Now()
+		more = es.delExpiredElems(expireBatchSize)
+		es.countPause(start)
+		es.unlock()
+	}
+
+	es.shrink()
 }
 
 
 // Add an element to the set normally.
 // If the element is existed,
-// its expiration time will be cleared if it has.
-func(es *ExpirableSet) Add(elem interface{}) {
-	es.mutex.Lock()
-	es.add(elem, nil)
-	es.mutex.Unlock()
+// its expiration time will be cleared if it has,
+// unless the set has a default ttl.
+func(es *Set[T]) Add(elem T) {
+	es.lock()
+	es.add(elem, es.defaultBase())
+	es.unlock()
 }
 
 
 // Add an element to the set with an expiration time.
 // If the element is existed,
 // its expiration time will be reset to new.
-func(es *ExpirableSet) AddWithExpire(elem interface{}, expireTime time.Duration) {
-	es.mutex.Lock()
+func(es *Set[T]) AddWithExpire(elem T, expireTime time.Duration) {
+	es.lock()
 	es.add(elem, es.buildBase(expireTime))
-	es.mutex.Unlock()
+	es.unlock()
+}
+
+
+// Add an element to the set which expires at the time.
+// If the element is existed,
+// its expiration time will be reset to new.
+func(es *Set[T]) AddWithExpireAt(elem T, expireAt time.Time) {
+	es.lock()
+	es.add(elem, base{expireAt: unixNano(expireAt), ttl: expireAt.Sub(es.now())})
+	es.unlock()
+}
+
+
+// Add the elements to the set like Add,
+// holding the lock once for all of them.
+func(es *Set[T]) AddAll(elems ...T) {
+	es.lock()
+	b := es.defaultBase()
+	for _, elem := range elems {
+		es.add(elem, b)
+	}
+	es.unlock()
+}
+
+
+// Add the elements to the set with an expiration time,
+// holding the lock once for all of them.
+func(es *Set[T]) AddAllWithExpire(expireTime time.Duration, elems ...T) {
+	es.lock()
+	b := es.buildBase(expireTime)
+	for _, elem := range elems {
+		es.add(elem, b)
+	}
+	es.unlock()
+}
+
+
+// Adds the entries holding the lock once,
+// each entry expires at its ExpireAt, or never if it's zero.
+func(es *Set[T]) addEntries(entries []Entry[T]) {
+	es.lock()
+	es.putEntries(entries)
+	es.unlock()
+}
+
+
+// Same as addEntries, but the write lock must be held.
+func(es *Set[T]) putEntries(entries []Entry[T]) {
+	now := es.now()
+	for _, entry := range entries {
+		es.add(entry.Elem, es.entryBase(entry, now))
+	}
+}
+
+
+// Returns the base of the entry, which has no ttl if its ExpireAt is zero,
+// even if the set has a default ttl, so restored elements keep never expiring.
+func(es *Set[T]) entryBase(entry Entry[T], now time.Time) base {
+	if entry.ExpireAt.IsZero() {
+		return base{}
+	}
+
+	return base{expireAt: entry.ExpireAt.UnixNano(), ttl: entry.ExpireAt.Sub(now)}
+}
+
+
+// Set the expiration time of an existed element,
+// whether it has one or not.
+// Returns false if the element doesn't exist.
+func(es *Set[T]) Expire(elem T, expireTime time.Duration) bool {
+	key := es.keyOf(elem)
+	es.lock()
+	defer es.unlock()
+	return es.setExpire(key, es.now().Add(expireTime))
+}
+
+
+// Set the time an existed element expires at.
+// Returns false if the element doesn't exist.
+func(es *Set[T]) ExpireAt(elem T, expireAt time.Time) bool {
+	key := es.keyOf(elem)
+	es.lock()
+	defer es.unlock()
+	return es.setExpire(key, expireAt)
+}
+
+
+func(es *Set[T]) setExpire(key T, expireAt time.Time) bool {
+	b, isExist := es.elems[key]
+	if !isExist || es.isExpired(b) {
+		return false
+	}
+
+	es.setExpireTime(key, b, expireAt, expireAt.Sub(es.now()))
+	return true
+}
+
+
+// Renew the expiration time of an existed element
+// by the ttl it was added with.
+// Returns false if the element doesn't exist.
+func(es *Set[T]) Touch(elem T) bool {
+	return es.touch(es.keyOf(elem))
+}
+
+
+// Remove the expiration time of an existed element,
+// so that it never expires, except for the max lifetime of the set.
+// Returns false if the element doesn't exist or doesn't have ttl.
+func(es *Set[T]) Persist(elem T) bool {
+	key := es.keyOf(elem)
+	es.lock()
+	defer es.unlock()
+
+	b, isExist := es.elems[key]
+	if !isExist || !b.hasTTL() || es.isExpired(b) {
+		return false
+	}
+
+	es.setExpireTime(key, b, time.Time{}, 0)
+	return true
 }
 
 
 // Update an existed element in the set,
 // and its expiration time will be inherited.
 // Returns an error if the element doesn't exist.
-func(es *ExpirableSet) Update(old interface{}, new interface{}) (err error) {
-	oldElem, isExist := es.elems[old]
+func(es *Set[T]) Update(old T, new T) (err error) {
+	oldKey := es.keyOf(old)
+	es.lock()
+	oldElem, isExist := es.elems[oldKey]
 	if isExist {
-		es.mutex.Lock()
-		es.elems[new] = oldElem
-		delete(es.elems, old)
-		es.mutex.Unlock()
+		es.del(oldKey)
+		es.pend(es.elemOf(oldKey, oldElem), Replaced)
+		es.add(new, oldElem)
 	} else {
-		err = errors.New("elem doesn't exist")
+		err = ErrElemNotExist
 	}
 
+	es.unlock()
 	return
 }
 
 
 // Remove an element in the set.
-// If the element doesn't exist, nothing will happen.
-func(es *ExpirableSet) Remove(elem interface{}) {
-	es.mutex.Lock()
-	delete(es.elems, elem)
-	es.mutex.Unlock()
+// Returns false if the element doesn't exist.
+func(es *Set[T]) Remove(elem T) bool {
+	key := es.keyOf(elem)
+	es.lock()
+	isExist := es.remove(key)
+	es.unlock()
+	return isExist
+}
+
+
+func(es *Set[T]) remove(key T) bool {
+	base, isExist := es.elems[key]
+	if isExist && es.isExpired(base) {
+		es.expire(key, base)
+		return false
+	} else if isExist {
+		es.del(key)
+		es.pend(es.elemOf(key, base), Removed)
+	}
+
+	return isExist
+}
+
+
+// Removes and returns an arbitrary unexpired element,
+// like SPOP of Redis, so that the set can be a pool of pending work.
+// The expired elements it comes across are deleted.
+// ok is false if the set is empty.
+func(es *Set[T]) Pop() (elem T, ok bool) {
+	es.lock()
+	defer es.unlock()
+
+	for key, base := range es.elems {
+		if es.isExpired(base) {
+			es.expire(key, base)
+			continue
+		}
+
+		elem = es.elemOf(key, base)
+		es.del(key)
+		es.pend(elem, Removed)
+		return elem, true
+	}
+
+	return elem, false
+}
+
+
+// Returns up to n arbitrary unexpired elements without removing them,
+// it stops once it has n, so it's cheap on large sets.
+// The iteration order of map is random, but not uniform,
+// so it doesn't suit algorithms that need unbiased samples.
+func(es *Set[T]) Random(n int) []T {
+	if n <= 0 {
+		return nil
+	}
+
+	samples := make([]T, 0, min(n, es.Len()))
+	elems, done := es.view()
+	for key, base := range elems {
+		if len(samples) == n {
+			break
+		}
+
+		if !es.isExpired(base) {
+			samples = append(samples, es.elemOf(key, base))
+		}
+	}
+
+	done()
+	return samples
+}
+
+
+// Remove the elements in the set,
+// holding the lock once for all of them.
+// Returns how many of them existed.
+func(es *Set[T]) RemoveAll(elems ...T) int {
+	removed := 0
+	es.lock()
+	for _, elem := range elems {
+		if es.remove(es.keyOf(elem)) {
+			removed++
+		}
+	}
+	es.unlock()
+	return removed
 }
 
 
@@ -146,26 +931,21 @@ func(es *ExpirableSet) Remove(elem interface{}) {
 // Although the manually removed and
 // expired elements disappear in the set,
 // they may not be released in memory for some reason.
-func(es *ExpirableSet) ClearEvictedElems() {
-	newElems := make(map[interface{}]*base)
-	es.mutex.Lock()
+// Sparse maps are also reallocated automatically
+// during cleanups, see WithShrinkRatio.
+func(es *Set[T]) ClearEvictedElems() {
+	newElems := make(map[T]base)
+	es.lock()
 	for elem, base := range es.elems {
 		newElems[elem] = base
 	}
 
 	es.elems = newElems
-	es.mutex.Unlock()
-}
-
-
-// Returns size and capacity of the set.
-func(es *ExpirableSet) Info() (size, capacity int) {
-	hmap := *(**hmap)(unsafe.Pointer(&es.elems))
-	if hmap.B == 0 {
-		return hmap.count, 8
-	}
-
-	return hmap.count, FACTOR * 2 << (int(hmap.B)-1)
+	es.peak = len(newElems)
+	es.dirty = nil
+	es.gen++
+	es.rebuildExpires()
+	es.unlock()
 }
 
 
@@ -173,199 +953,712 @@ func(es *ExpirableSet) Info() (size, capacity int) {
 // Get ttl of the element.
 // Returns an error if the element doesn't exist,
 // or if the element doesn't have ttl.
-func(es *ExpirableSet) GetElemTTL(elem interface{}) (ttl float64, err error) {
-	es.mutex.RLock()
-	base, isExist := es.elems[elem]
-	es.mutex.RUnlock()
+func(es *Set[T]) GetElemTTL(elem T) (ttl float64, err error) {
+	base, isExist := es.lookup(es.keyOf(elem))
+	now := es.now()
 
-	now := time.Now()
 	ttl = -1
 	if !isExist {
-		err = errors.New("elem doesn't exist")
-	} else if base == nil {
-		err = errors.New("elem doesn't have ttl")
-	} else if base.expireTime.After(now) {
-		ttl = base.expireTime.Sub(now).Seconds()
+		err = ErrElemNotExist
+	} else if !base.hasTTL() {
+		err = ErrElemNoTTL
+	} else if base.expireTime().After(now) {
+		ttl = base.expireTime().Sub(now).Seconds()
 	} else {
-		err = errors.New("elem doesn't exist")
+		err = ErrElemNotExist
 	}
 
 	return ttl, err
 }
 
 
+// Returns the remaining time to live of the element.
+// ok is false if the element doesn't exist or doesn't have ttl.
+func(es *Set[T]) TTL(elem T) (ttl time.Duration, ok bool) {
+	base, isExist := es.lookup(es.keyOf(elem))
+	now := es.now()
+
+	if !isExist || !base.hasTTL() {
+		return 0, false
+	}
+
+	ttl = base.expireTime().Sub(now)
+	if ttl <= 0 {
+		return 0, false
+	}
+
+	return ttl, true
+}
+
+
+// Returns the time the element expires at.
+// ok is false if the element doesn't exist or doesn't have ttl.
+func(es *Set[T]) GetExpireAt(elem T) (expireAt time.Time, ok bool) {
+	base, isExist := es.lookup(es.keyOf(elem))
+	if !isExist || !base.hasTTL() || es.isExpired(base) {
+		return time.Time{}, false
+	}
+
+	return base.expireTime(), true
+}
+
+
 // Returns a slice that has all unexpired elements.
-func(es *ExpirableSet) GetAll() []interface{} {
-	es.mutex.Lock()
-	var tempSlice []interface{}
+func(es *Set[T]) GetAll() []T {
+	return es.AppendTo(nil)
+}
+
+
+// Appends all unexpired elements to dst and returns the extended slice,
+// so that a slice can be reused across calls instead of allocating one each time,
+// e.g. buf = es.AppendTo(buf[:0]).
+func(es *Set[T]) AppendTo(dst []T) []T {
+	if elems := es.snapshot.Load(); elems != nil {
+		for elem, base := range *elems {
+			if !es.isExpired(base) {
+				dst = append(dst, es.elemOf(elem, base))
+			}
+		}
+
+		return dst
+	}
+
+	es.lock()
 	for elem, base := range es.elems {
-		if base.isExpired() {
-			delete(es.elems, elem)
+		if es.isExpired(base) {
+			es.expire(elem, base)
 		} else {
-			tempSlice = append(tempSlice, elem)
+			dst = append(dst, es.elemOf(elem, base))
 		}
 	}
 
-	es.mutex.Unlock()
-	return tempSlice
+	es.unlock()
+	return dst
 }
 
 
-func(es *ExpirableSet) Contains(elem interface{}) bool {
-	es.mutex.RLock()
-	base, isExist := es.elems[elem]
-	es.mutex.RUnlock()
-	return isExist && !base.isExpired()
+// Returns true if the element is in the set,
+// or in its store if it has one, see WithStore.
+// In sliding expiration mode, its ttl is renewed as well.
+func(es *Set[T]) Contains(elem T) bool {
+	return es.countLookup(es.lookupElem(elem))
 }
 
 
-func(es *ExpirableSet) Clear() {
+func(es *Set[T]) lookupElem(elem T) bool {
+	key := es.keyOf(elem)
+	if es.sliding {
+		return es.touch(key) || es.store != nil && es.loadFromStore(elem)
+	}
+
+	var isExist, isExpired bool
+	if es.canLookup() {
+		var base base
+		base, isExist = es.lookup(key)
+		isExpired = isExist && es.isExpired(base)
+		isExist = isExist && !isExpired
+	} else {
+		es.rlock()
+		isExist, isExpired = es.has(key)
+		es.runlock()
+	}
+
+	if isExpired && es.expireOnRead {
+		es.expireKey(key)
+	}
+	if !isExist && es.store != nil {
+		return es.loadFromStore(elem)
+	}
+	return isExist
+}
+
+
+// Deletes the element if it has expired.
+func(es *Set[T]) expireKey(key T) {
+	es.lock()
+	if b, isExist := es.elems[key]; isExist && es.isExpired(b) {
+		es.expire(key, b)
+	}
+	es.unlock()
+}
+
+
+// Returns whether Contains can look up the elements without the lock,
+// which is when nothing has to be recorded for the accesses.
+func(es *Set[T]) canLookup() bool {
+	return (es.readMap != nil || es.copyOnWrite) && es.maxSize <= 0 && es.maxWeight <= 0
+}
+
+
+// Returns true if the key is in the set,
+// and records the access for the eviction policy.
+// isExpired is true if it's in the map but has expired.
+// The read lock must be held.
+func(es *Set[T]) has(key T) (isExist, isExpired bool) {
+	base, isExist := es.elems[key]
+	isExpired = isExist && es.isExpired(base)
+	isExist = isExist && !isExpired
+	if isExist && es.evictor != nil {
+		es.evictor.access(key)
+	} else if filter, ok := es.evictor.(*tinyLFU[T]); ok {
+		// misses count for the admission filter too
+		filter.record(key)
+	}
+
+	return isExist, isExpired
+}
+
+
+// Returns true if the element is in the set,
+// without renewing its ttl in sliding expiration mode
+// or counting as an access for the eviction policy.
+func(es *Set[T]) Peek(elem T) bool {
+	base, isExist := es.lookup(es.keyOf(elem))
+	return isExist && !es.isExpired(base)
+}
+
+
+// Removes all the elements from the set.
+// The map is replaced rather than emptied,
+// and the old one is left to the garbage collector.
+// That doesn't make it constant time for every set:
+// WithChangeLog records each element as removed,
+// and WithStore deletes each element of the store once the lock is released.
+// Removed elements are not reported to the callbacks.
+func(es *Set[T]) Clear() {
+	es.lock()
+	es.gen++
 	es.init()
+	es.unlock()
 }
 
 
 // Returns true if the set is
 // the subset of the other set.
-func(es *ExpirableSet) IsSubSet(other *ExpirableSet) bool {
-	if es.largerThan(other) {
+// The expired elements of both sets are ignored.
+func(es *Set[T]) IsSubSet(other *Set[T]) bool {
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
+
+	isSubSet, _ := subSetOf(es, elems, other, otherElems)
+	return isSubSet
+}
+
+
+// Returns true if the set is
+// the superset of the other set, see IsSubSet.
+func(es *Set[T]) IsSuperSet(other *Set[T]) bool {
+	return other.IsSubSet(es)
+}
+
+
+// Returns true if the set is the subset of the other set,
+// and the other set has more elements, see IsSubSet.
+func(es *Set[T]) IsProperSubset(other *Set[T]) bool {
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
+
+	isSubSet, size := subSetOf(es, elems, other, otherElems)
+	if !isSubSet {
 		return false
 	}
 
-	es.mutex.RLock()
-	other.mutex.RLock()
-	for elem := range es.elems {
-		if !other.contains(elem) {
-			es.mutex.RUnlock()
-			other.mutex.RUnlock()
+	return other.countUnexpired(otherElems) > size
+}
+
+
+// Returns whether the unexpired elements of the set
+// are all unexpired in the other set,
+// and how many unexpired elements the set has.
+func subSetOf[T comparable](es *Set[T], elems map[T]base, other *Set[T], otherElems map[T]base) (bool, int) {
+	now, otherNow := es.nowNano(), other.nowNano()
+	size := 0
+	for elem, base := range elems {
+		if base.isExpired(now) {
+			continue
+		}
+
+		size++
+		if otherBase, isExist := otherElems[elem]; !isExist || otherBase.isExpired(otherNow) {
+			return false, size
+		}
+	}
+
+	return true, size
+}
+
+
+// Returns true if the two sets share no unexpired elements,
+// it stops at the first common one.
+func(es *Set[T]) Disjoint(other *Set[T]) bool {
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
+
+	smallEs, smallElems, largeEs, largeElems := es, elems, other, otherElems
+	if len(elems) > len(otherElems) {
+		smallEs, smallElems, largeEs, largeElems = other, otherElems, es, elems
+	}
+
+	now, largeNow := smallEs.nowNano(), largeEs.nowNano()
+	for elem, base := range smallElems {
+		if base.isExpired(now) {
+			continue
+		}
+		if largeBase, isExist := largeElems[elem]; isExist && !largeBase.isExpired(largeNow) {
 			return false
 		}
 	}
 
-	es.mutex.RUnlock()
-	other.mutex.RUnlock()
 	return true
 }
 
 
-func(es *ExpirableSet) Union(other *ExpirableSet) *ExpirableSet {
-	lagerEs, smallEs := compareAndGet(es, other)
-	smallEs.mutex.RLock()
-	for elem := range smallEs.elems {
-		if !lagerEs.contains(elem) {
-			lagerEs.elems[elem] = smallEs.elems[elem]
+// Returns the union of the two sets as a new set,
+// neither of them is changed.
+// The expiration time of an element in both is decided by WithTTLMergePolicy.
+// Both operands and the result have the same element type,
+// so mixing sets of different types doesn't compile.
+func(es *Set[T]) Union(other *Set[T]) *Set[T] {
+	newEs := es.derive()
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
+
+	for elem, base := range otherElems {
+		if _, isExist := elems[elem]; !isExist {
+			newEs.put(elem, base)
 		}
 	}
+	for elem, base := range elems {
+		if otherBase, isExist := otherElems[elem]; isExist {
+			base = es.mergeBase(base, otherBase)
+		}
+		newEs.put(elem, base)
+	}
 
-	smallEs.mutex.RUnlock()
-	return lagerEs
+	return newEs
 }
 
 
-func(es *ExpirableSet) Intersect(other *ExpirableSet) *ExpirableSet {
-	newEs := New()
-	var lagerEs, smallEs *ExpirableSet
-	if es.largerThan(other) {
-		lagerEs, smallEs = es, other
-	} else {
-		lagerEs, smallEs = other, es
+// Add the elements of the other set that are not in this set,
+// with their expiration time, which is Union in place.
+// The expiration time of the elements already in this set
+// is decided by WithTTLMergePolicy.
+func(es *Set[T]) UnionWith(other *Set[T]) {
+	if es == other {
+		return
 	}
 
-	lagerEs.mutex.RLock()
-	smallEs.mutex.RLock()
-	for elem := range smallEs.elems {
-		if lagerEs.contains(elem) {
-			newEs.elems[elem] = smallEs.elems[elem]
+	otherElems := other.copyElems()
+	es.lock()
+	for elem, base := range otherElems {
+		es.mergeInto(elem, base, true)
+	}
+	es.unlock()
+}
+
+
+// Add the elements of the other set in place.
+//
+// Deprecated: use UnionWith instead.
+func(es *Set[T]) Merge(other *Set[T]) {
+	es.UnionWith(other)
+}
+
+
+// Remove the elements that are not in the other set,
+// which is Intersect in place.
+// The expiration time of the remaining elements
+// is decided by WithTTLMergePolicy.
+func(es *Set[T]) IntersectWith(other *Set[T]) {
+	if es == other {
+		return
+	}
+
+	otherElems := other.copyElems()
+	es.lock()
+	for elem := range es.elems {
+		if base, isExist := otherElems[elem]; !isExist {
+			es.remove(elem)
+		} else {
+			es.mergeInto(elem, base, false)
+		}
+	}
+	es.unlock()
+}
+
+
+// Merges the base of an element of another set into the set,
+// the element is added if it's not in the set and add is true.
+// The write lock must be held.
+func(es *Set[T]) mergeInto(key T, b base, add bool) {
+	old, isExist := es.elems[key]
+	if !isExist {
+		if add {
+			es.put(key, b)
+		}
+		return
+	}
+
+	if merged := es.mergeBase(old, b); merged != old {
+		es.put(key, merged)
+	}
+}
+
+
+// Remove the elements that are in the other set,
+// which is Difference in place.
+func(es *Set[T]) DifferenceWith(other *Set[T]) {
+	if es == other {
+		es.Clear()
+		return
+	}
+
+	otherElems := other.copyElems()
+	es.lock()
+	for elem := range otherElems {
+		es.remove(elem)
+	}
+	es.unlock()
+}
+
+
+// Remove the elements that are not given,
+// holding the lock once.
+// Returns how many elements were removed.
+func(es *Set[T]) RetainAll(elems ...T) int {
+	retained := make(map[T]struct{}, len(elems))
+	for _, elem := range elems {
+		retained[es.keyOf(elem)] = struct{}{}
+	}
+
+	removed := 0
+	es.lock()
+	for key := range es.elems {
+		if _, isExist := retained[key]; !isExist && es.remove(key) {
+			removed++
+		}
+	}
+	es.unlock()
+	return removed
+}
+
+
+// Returns a copy of the map,
+// so that it can be read while another set is locked
+// without locking the two sets together.
+func(es *Set[T]) copyElems() map[T]base {
+	elems, done := es.view()
+	defer done()
+	return maps.Clone(elems)
+}
+
+
+// Returns the intersection of the two sets,
+// which has the same element type as them.
+// The expiration time of the elements is decided by WithTTLMergePolicy.
+func(es *Set[T]) Intersect(other *Set[T]) *Set[T] {
+	newEs := es.derive()
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
+
+	smallElems, largeElems := elems, otherElems
+	if len(elems) > len(otherElems) {
+		smallElems, largeElems = otherElems, elems
+	}
+
+	for elem := range smallElems {
+		if _, isExist := largeElems[elem]; isExist {
+			newEs.put(elem, es.mergeBase(elems[elem], otherElems[elem]))
 		}
 	}
 
-	lagerEs.mutex.RUnlock()
-	smallEs.mutex.RUnlock()
 	return newEs
 }
 
 
-func(es *ExpirableSet) Different(other *ExpirableSet) *ExpirableSet {
-	lagerEs, smallEs := compareAndGet(es, other)
+// Returns the elements of this set that are not in the other set
+// as a new set, neither of them is changed.
+func(es *Set[T]) Difference(other *Set[T]) *Set[T] {
+	newEs := es.derive()
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
 
-	smallEs.mutex.RLock()
-	for elem := range smallEs.elems {
-		if lagerEs.contains(elem) {
-			delete(lagerEs.elems, elem)
-		} else {
-			lagerEs.elems[elem] = smallEs.elems[elem]
+	for elem, base := range elems {
+		if _, isExist := otherElems[elem]; !isExist {
+			newEs.put(elem, base)
+		}
+	}
+
+	return newEs
+}
+
+
+// Returns the elements that are in only one of the two sets as a new set,
+// neither of them is changed.
+// Each element keeps the expiration time it has in the set it comes from.
+// The result has the same element type as them.
+func(es *Set[T]) SymmetricDifference(other *Set[T]) *Set[T] {
+	newEs := es.derive()
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
+
+	for elem, base := range elems {
+		if _, isExist := otherElems[elem]; !isExist {
+			newEs.put(elem, base)
+		}
+	}
+	for elem, base := range otherElems {
+		if _, isExist := elems[elem]; !isExist {
+			newEs.put(elem, base)
+		}
+	}
+
+	return newEs
+}
+
+
+// Deprecated: use SymmetricDifference,
+// or Difference for the elements of this set that are not in the other set.
+func(es *Set[T]) Different(other *Set[T]) *Set[T] {
+	return es.SymmetricDifference(other)
+}
+
+
+// Returns the union of the sets as a new set,
+// which is built in one pass, so it's cheaper than
+// calling Union on them one by one.
+// None of them is changed, and the sets are locked one at a time.
+// The expiration time of an element in several sets is decided
+// by the WithTTLMergePolicy of the first set,
+// whose options the result inherits.
+func UnionAll[T comparable](sets ...*Set[T]) *Set[T] {
+	if len(sets) == 0 {
+		return NewSet[T]()
+	}
+
+	total := 0
+	for _, es := range sets {
+		total += es.Len()
+	}
+
+	newEs := sets[0].deriveSized(total)
+	for _, es := range sets {
+		elems, done := es.view()
+		for elem, base := range elems {
+			newEs.mergeInto(elem, base, true)
 		}
+		done()
 	}
 
-	smallEs.mutex.RUnlock()
-	return lagerEs
+	return newEs
+}
+
+
+// Returns the intersection of the sets as a new set,
+// see UnionAll.
+func IntersectAll[T comparable](sets ...*Set[T]) *Set[T] {
+	if len(sets) == 0 {
+		return NewSet[T]()
+	}
+
+	smallest := sets[0]
+	for _, es := range sets[1:] {
+		if es.Len() < smallest.Len() {
+			smallest = es
+		}
+	}
+
+	// the bases are merged in the order of the sets
+	common := smallest.copyElems()
+	for i, es := range sets {
+		if len(common) == 0 {
+			break
+		}
+
+		elems, done := es.view()
+		for elem, merged := range common {
+			base, isExist := elems[elem]
+			switch {
+			case !isExist:
+				delete(common, elem)
+			case i == 0:
+				common[elem] = base
+			default:
+				common[elem] = sets[0].mergeBase(merged, base)
+			}
+		}
+		done()
+	}
+
+	newEs := sets[0].deriveSized(len(common))
+	for elem, base := range common {
+		newEs.put(elem, base)
+	}
+
+	return newEs
 }
 
 
 // Ignore the order to determine
 // whether the elements in the set are equal.
-func(es *ExpirableSet) Equal(other *ExpirableSet) bool {
-	if len(es.elems) != len(other.elems) {
+// The expired elements that are not deleted yet are counted,
+// use EqualOpts to ignore them.
+func(es *Set[T]) Equal(other *Set[T]) bool {
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
+
+	if len(elems) != len(otherElems) {
 		return false
 	}
 
-	es.mutex.RLock()
-	other.mutex.RLock()
-
-	for elem := range other.elems {
-		if !es.contains(elem) {
-			es.mutex.RUnlock()
-			other.mutex.RUnlock()
+	for elem := range otherElems {
+		if _, isExist := elems[elem]; !isExist {
 			return false
 		}
 	}
 
-	es.mutex.RUnlock()
-	other.mutex.RUnlock()
 	return true
 }
 
 
-func(es *ExpirableSet) Clone() *ExpirableSet {
-	return &ExpirableSet{
-		elems:    es.elems,
-		capacity: es.capacity,
+// Returns a copy of the set with its own map,
+// so changing either of them doesn't affect the other.
+// The expired elements that are not deleted yet are copied as well.
+func(es *Set[T]) Clone() *Set[T] {
+	es.rlock()
+	defer es.runlock()
+
+	if es.evictor != nil || es.weigher != nil {
+		// the elements are put one by one to track their weights and eviction order
+		newEs := es.deriveSized(len(es.elems))
+		for key, b := range es.elems {
+			newEs.put(key, b)
+		}
+		return newEs
 	}
+
+	newEs := &Set[T]{
+		elems:  maps.Clone(es.elems),
+		config: es.config.inherited(),
+	}
+	newEs.count.Store(int64(len(newEs.elems)))
+	if es.expires != nil {
+		newEs.expires = es.expires.clone()
+	}
+
+	return newEs
 }
 
 
-func(es *ExpirableSet) Size() int {
-	es.mutex.Lock()
-	es.delExpiredElems()
-	es.mutex.Unlock()
-	return len(es.elems)
+// Returns a copy of the unexpired elements of the set at this moment,
+// which is independent from the set, so long-running consumers
+// can walk it without blocking the writers of the set.
+// The copy keeps the expiration time of the elements,
+// but doesn't inherit the callbacks nor start a janitor.
+func(es *Set[T]) Snapshot() *Set[T] {
+	newEs := es.derive()
+	elems, done := es.view()
+	for key, base := range elems {
+		if !es.isExpired(base) {
+			newEs.put(key, base)
+		}
+	}
+
+	done()
+	return newEs
+}
+
+
+// Returns the number of unexpired elements,
+// the expired ones are deleted first,
+// so it takes the write lock if any has expired.
+// Use Len for a cheaper count.
+func(es *Set[T]) Size() int {
+	es.cleanExpired()
+	es.rlock()
+	size := len(es.elems)
+	es.runlock()
+	return size
+}
+
+
+// Returns the max size of WithMaxSize, 0 if the set has none.
+func(es *Set[T]) MaxSize() int {
+	return es.maxSize
+}
+
+
+// Returns the number of elements without taking the lock,
+// which suits metrics read on every request.
+// It's best effort: the expired elements are counted
+// until they are deleted, by the janitor for example.
+func(es *Set[T]) Len() int {
+	return int(es.count.Load())
 }
 
 
 // Do something for each elements in the set.
-func(es *ExpirableSet) ForEach(handler func(interface{})) {
-	es.mutex.Lock()
+// The write lock is held while the handler runs,
+// walk a Snapshot instead if the handler is slow.
+func(es *Set[T]) ForEach(handler func(T)) {
+	if elems := es.snapshot.Load(); elems != nil {
+		for elem, base := range *elems {
+			if !es.isExpired(base) {
+				handler(es.elemOf(elem, base))
+			}
+		}
+
+		return
+	}
+
+	es.lock()
 	for elem, base := range es.elems {
-		if base.isExpired() {
-			delete(es.elems, elem)
+		if es.isExpired(base) {
+			es.expire(elem, base)
 			continue
 		}
 
-		handler(elem)
+		handler(es.elemOf(elem, base))
 	}
-	es.mutex.Unlock()
+	es.unlock()
 }
 
 
-func(b *base) isExpired() bool {
-	return b != nil && b.expireTime.Before(time.Now())
+func(b base) isExpired(now int64) bool {
+	return b.hasTTL() && b.expireAt < now
 }
 
 
-// Compare two set's size.
-// Returns the bigger one's clone and the smaller one.
-func compareAndGet(one, other *ExpirableSet) (*ExpirableSet, *ExpirableSet) {
-	if one.largerThan(other) {
-		return one.Clone(), other
+func(b base) hasTTL() bool {
+	return b.expireAt != 0
+}
+
+
+// Returns the time the element expires at, zero if it has no ttl.
+func(b base) expireTime() time.Time {
+	if b.expireAt == 0 {
+		return time.Time{}
 	}
-	return other.Clone(), one
+
+	return time.Unix(0, b.expireAt)
 }
+
+
+func(b base) deadline() int64 {
+	if b.ext == nil {
+		return 0
+	}
+
+	return b.ext.deadline
+}
+
+
+// Returns the unix nano of the time, 0 if it's zero.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
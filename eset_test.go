@@ -0,0 +1,761 @@
+package eset
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSet(t *testing.T) {
+	es := NewSet[string]()
+	for i := 0; i < 3; i++ {
+		es.Add("foo")
+	}
+	es.Add("bar")
+
+	if es.Size() != 2 {
+		t.Fatalf("size = %d, want 2", es.Size())
+	}
+	if !es.Contains("foo") || !es.Contains("bar") || es.Contains("baz") {
+		t.Fatalf("has %v, want [bar foo]", es.GetAll())
+	}
+	if got := sorted(es.GetAll()); !equalStrings(got, []string{"bar", "foo"}) {
+		t.Fatalf("GetAll() = %v, want [bar foo]", got)
+	}
+
+	es.Remove("foo")
+	if es.Contains("foo") || es.Size() != 1 {
+		t.Fatalf("has %v after removing foo", es.GetAll())
+	}
+
+	es.Clear()
+	if es.Size() != 0 || len(es.GetAll()) != 0 {
+		t.Fatalf("has %v after clear", es.GetAll())
+	}
+}
+
+
+func TestSetExpire(t *testing.T) {
+	es := NewSet[string]()
+	es.Add("forever")
+	es.AddWithExpire("short", time.Millisecond)
+	es.AddWithExpire("long", time.Hour)
+
+	time.Sleep(5 * time.Millisecond)
+	if es.Contains("short") {
+		t.Fatal("expired element is still contained")
+	}
+	if got := sorted(es.GetAll()); !equalStrings(got, []string{"forever", "long"}) {
+		t.Fatalf("GetAll() = %v, want [forever long]", got)
+	}
+	if es.Size() != 2 {
+		t.Fatalf("size = %d, want 2", es.Size())
+	}
+
+	if ttl, err := es.GetElemTTL("long"); err != nil || ttl <= 0 || ttl > time.Hour.Seconds() {
+		t.Fatalf("GetElemTTL(long) = %v, %v", ttl, err)
+	}
+	if _, err := es.GetElemTTL("forever"); err == nil {
+		t.Fatal("GetElemTTL of an element without ttl doesn't fail")
+	}
+	if _, err := es.GetElemTTL("missing"); err == nil {
+		t.Fatal("GetElemTTL of a missing element doesn't fail")
+	}
+}
+
+
+func TestSetUpdate(t *testing.T) {
+	es := NewSet[int]()
+	es.AddWithExpire(1, time.Hour)
+
+	if err := es.Update(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if es.Contains(1) || !es.Contains(2) {
+		t.Fatalf("has %v after update, want [2]", es.GetAll())
+	}
+	if _, err := es.GetElemTTL(2); err != nil {
+		t.Fatal("updated element lost its ttl")
+	}
+	if err := es.Update(3, 4); err == nil {
+		t.Fatal("updating a missing element doesn't fail")
+	}
+}
+
+
+func TestExpirableSet(t *testing.T) {
+	es := New()
+	es.Add(1)
+	es.Add("1")
+	es.Add(1.0)
+
+	if es.Size() != 3 {
+		t.Fatalf("size = %d, want 3", es.Size())
+	}
+	if !es.Contains(1) || !es.Contains("1") || es.Contains(int64(1)) {
+		t.Fatalf("has %v", es.GetAll())
+	}
+}
+
+
+func TestSetOperations(t *testing.T) {
+	if got := sortedInts(setOf(1, 2, 3).Union(setOf(3, 4)).GetAll()); !equalInts(got, []int{1, 2, 3, 4}) {
+		t.Fatalf("union = %v, want [1 2 3 4]", got)
+	}
+	if got := setOf(1, 2, 3).Intersect(setOf(3, 4)).GetAll(); !equalInts(got, []int{3}) {
+		t.Fatalf("intersection = %v, want [3]", got)
+	}
+
+	if !setOf(1, 2).IsSubSet(setOf(1, 2, 3)) || setOf(1, 2, 3).IsSubSet(setOf(1, 2)) {
+		t.Fatal("wrong subset relation")
+	}
+	if !setOf(1, 2).Equal(setOf(2, 1)) || setOf(1, 2).Equal(setOf(1, 3)) {
+		t.Fatal("wrong equality")
+	}
+}
+
+
+func TestSetErrors(t *testing.T) {
+	es := NewSet[int]()
+	es.Add(1)
+
+	if _, err := es.GetElemTTL(1); !errors.Is(err, ErrElemNoTTL) {
+		t.Fatalf("GetElemTTL(1) error = %v, want ErrElemNoTTL", err)
+	}
+	if _, err := es.GetElemTTL(2); !errors.Is(err, ErrElemNotExist) {
+		t.Fatalf("GetElemTTL(2) error = %v, want ErrElemNotExist", err)
+	}
+	if !errors.Is(es.Update(2, 3), ErrElemNotExist) {
+		t.Fatal("Update of a missing element doesn't return ErrElemNotExist")
+	}
+
+	if !es.Remove(1) || es.Remove(1) {
+		t.Fatal("Remove doesn't report whether the element existed")
+	}
+
+	es.AddWithExpire(4, time.Hour)
+	if ttl, ok := es.TTL(4); !ok || ttl <= 59 * time.Minute || ttl > time.Hour {
+		t.Fatalf("TTL(4) = %v, %v", ttl, ok)
+	}
+	if _, ok := es.TTL(5); ok {
+		t.Fatal("TTL of a missing element is ok")
+	}
+}
+
+
+type record struct {
+	ID   int
+	Tags []string
+}
+
+
+func TestHasher(t *testing.T) {
+	es := New(WithHasher(func(elem interface{}) uint64 {
+		return uint64(elem.(record).ID)
+	}))
+	es.Add(record{ID: 1, Tags: []string{"a"}})
+	es.AddWithExpire(record{ID: 2}, time.Hour)
+	es.Add(record{ID: 1, Tags: []string{"b"}})
+
+	if es.Size() != 2 {
+		t.Fatalf("size = %d, want 2", es.Size())
+	}
+	if !es.Contains(record{ID: 1}) || !es.Contains(record{ID: 2}) || es.Contains(record{ID: 3}) {
+		t.Fatalf("has %v", es.GetAll())
+	}
+	if _, err := es.GetElemTTL(record{ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := es.Update(record{ID: 1}, record{ID: 3}); err != nil || !es.Contains(record{ID: 3}) {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	es.Remove(record{ID: 2})
+	if got := es.GetAll(); len(got) != 1 || got[0].(record).ID != 3 {
+		t.Fatalf("has %v, want the record 3", got)
+	}
+}
+
+
+func TestConstructors(t *testing.T) {
+	es := FromSlice([]string{"a", "b", "a"}, time.Hour)
+	if es.Size() != 2 {
+		t.Fatalf("FromSlice has %v, want [a b]", es.GetAll())
+	}
+	if _, err := es.GetElemTTL("a"); err != nil {
+		t.Fatal("FromSlice didn't give the ttl")
+	}
+
+	es = FromMap(map[string]time.Duration{"a": 0, "b": time.Hour})
+	if _, err := es.GetElemTTL("a"); err == nil {
+		t.Fatal("FromMap gave a ttl to the key mapped to 0")
+	}
+	if _, err := es.GetElemTTL("b"); err != nil {
+		t.Fatal("FromMap didn't give the ttl")
+	}
+
+	ch := make(chan string, 3)
+	ch <- "a"
+	ch <- "b"
+	ch <- "a"
+	close(ch)
+	if got := sorted(FromChannel(ch).GetAll()); !equalStrings(got, []string{"a", "b"}) {
+		t.Fatalf("FromChannel has %v, want [a b]", got)
+	}
+}
+
+
+type user struct {
+	ID   int
+	Name string
+}
+
+
+func TestKeyFunc(t *testing.T) {
+	es := New(WithKeyFunc(func(elem interface{}) interface{} {
+		if u, ok := elem.(user); ok {
+			return u.ID
+		}
+		return elem
+	}))
+	es.Add(user{1, "alice"})
+	es.Add(user{1, "bob"})
+	es.Add(user{2, "carol"})
+
+	if es.Size() != 2 || !es.Contains(1) || !es.Contains(user{ID: 2}) {
+		t.Fatalf("has %v", es.GetAll())
+	}
+	for _, elem := range es.GetAll() {
+		if u := elem.(user); u.ID == 1 && u.Name != "bob" {
+			t.Fatalf("kept %v, want the last added", u)
+		}
+	}
+
+	es.Remove(1)
+	if es.Contains(user{ID: 1}) {
+		t.Fatal("removing by key didn't remove the element")
+	}
+}
+
+
+func TestContainsAllocs(t *testing.T) {
+	strs := NewSet[string]()
+	key := string([]byte("hello-world-key"))
+	strs.Add(key)
+	if n := testing.AllocsPerRun(100, func() { strs.Contains(key) }); n != 0 {
+		t.Fatalf("Contains of a string set allocates %v times", n)
+	}
+
+	ints := NewSet[int64]()
+	ints.Add(12345678)
+	if n := testing.AllocsPerRun(100, func() { ints.Contains(12345678) }); n != 0 {
+		t.Fatalf("Contains of an int set allocates %v times", n)
+	}
+}
+
+
+type point struct {
+	X, Y int
+}
+
+
+func TestSetOperationsKeepType(t *testing.T) {
+	one := setOf(point{0, 0}, point{1, 1})
+	other := setOf(point{1, 1}, point{2, 2})
+
+	var union *Set[point] = one.Union(other)
+	for _, p := range union.GetAll() {
+		if p.X != p.Y {
+			t.Fatalf("union has %v", p)
+		}
+	}
+	if union.Size() != 3 {
+		t.Fatalf("union has %v, want 3 points", union.GetAll())
+	}
+
+	var intersection *Set[point] = setOf(point{0, 0}, point{1, 1}).Intersect(setOf(point{1, 1}, point{2, 2}))
+	if got := intersection.GetAll(); len(got) != 1 || got[0] != (point{1, 1}) {
+		t.Fatalf("intersection = %v, want [{1 1}]", got)
+	}
+}
+
+
+func TestAddWithExpireAllocs(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 100; i++ {
+		es.AddWithExpire(i, time.Hour)
+	}
+
+	// the metadata is stored in the map by value
+	if n := testing.AllocsPerRun(1000, func() { es.AddWithExpire(5, time.Hour) }); n > 0.1 {
+		t.Fatalf("AddWithExpire allocates %v times", n)
+	}
+}
+
+
+func TestAddAll(t *testing.T) {
+	es := NewSet[int]()
+	es.AddAll(1, 2, 3)
+	es.AddAllWithExpire(time.Hour, 4, 5)
+
+	if es.Size() != 5 {
+		t.Fatalf("size = %d, want 5", es.Size())
+	}
+	if _, ok := es.TTL(5); !ok {
+		t.Fatal("AddAllWithExpire didn't give the ttl")
+	}
+	if n := es.RemoveAll(1, 4, 9); n != 2 {
+		t.Fatalf("RemoveAll removed %d, want 2", n)
+	}
+	if got := sortedInts(es.GetAll()); !equalInts(got, []int{2, 3, 5}) {
+		t.Fatalf("has %v, want [2 3 5]", got)
+	}
+}
+
+
+func TestConcurrentSetOperations(t *testing.T) {
+	// each operation locks both sets, in opposite orders here
+	one := setOf(1, 2, 3)
+	other := setOf(1, 2)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				switch g % 4 {
+				case 0:
+					one.Equal(other)
+					other.IsSubSet(one)
+				case 1:
+					other.Equal(one)
+					one.Intersect(other)
+				case 2:
+					one.Add(i % 5)
+					one.Remove(i % 5)
+				case 3:
+					other.Add(i % 5)
+					other.Remove(i % 5)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if !one.Equal(one) || !one.IsSubSet(one) {
+		t.Fatal("set is not equal to itself")
+	}
+}
+
+
+func TestClear(t *testing.T) {
+	es := NewSet[int](WithMaxSize(100))
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if g == 0 {
+					es.Clear()
+				} else {
+					es.Add(i)
+					es.Contains(i)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	es.AddWithExpire(1, time.Hour)
+	es.Clear()
+	if es.Size() != 0 || es.Contains(1) {
+		t.Fatalf("has %v after clear", es.GetAll())
+	}
+	es.Add(2)
+	if got := es.GetAll(); !equalInts(got, []int{2}) {
+		t.Fatalf("has %v, want [2]", got)
+	}
+}
+
+
+func TestAppendTo(t *testing.T) {
+	es := setOf(1, 2, 3)
+	buf := make([]int, 0, 8)
+	buf = es.AppendTo(buf[:0])
+	buf = es.AppendTo(buf[:0])
+	if got := sortedInts(buf); !equalInts(got, []int{1, 2, 3}) || cap(buf) != 8 {
+		t.Fatalf("got %v, want [1 2 3] in the given buffer", got)
+	}
+
+	if n := testing.AllocsPerRun(100, func() { buf = es.AppendTo(buf[:0]) }); n != 0 {
+		t.Fatalf("AppendTo allocates %v times", n)
+	}
+}
+
+
+func TestSnapshot(t *testing.T) {
+	es := setOf(1, 2, 3)
+	es.AddWithExpire(4, time.Hour)
+
+	snapshot := es.Snapshot()
+	snapshot.ForEach(func(elem int) {
+		// it doesn't deadlock
+		es.Add(elem + 10)
+	})
+	if snapshot.Size() != 4 || es.Size() != 8 {
+		t.Fatalf("snapshot has %d elements and the set %d, want 4 and 8", snapshot.Size(), es.Size())
+	}
+	if _, ok := snapshot.TTL(4); !ok {
+		t.Fatal("snapshot lost the ttl")
+	}
+}
+
+
+func TestZeroValue(t *testing.T) {
+	var es ExpirableSet
+	if es.Size() != 0 || es.Contains(1) || len(es.GetAll()) != 0 {
+		t.Fatal("zero value isn't empty")
+	}
+	es.Add(1)
+	es.AddWithExpire(2, time.Hour)
+	if !es.Contains(1) || es.Size() != 2 {
+		t.Fatalf("has %v, want [1 2]", es.GetAll())
+	}
+
+	var typed Set[int]
+	typed.Clear()
+	typed.Add(3)
+	if !typed.Contains(3) || typed.Union(&typed).Size() != 1 {
+		t.Fatalf("has %v, want [3]", typed.GetAll())
+	}
+}
+
+
+func TestLen(t *testing.T) {
+	es := NewSet[int](WithMaxSize(50))
+	for i := 0; i < 100; i++ {
+		es.Add(i)
+		es.Add(i)
+	}
+	es.Remove(99)
+	es.Remove(99)
+	es.AddWithExpire(1000, time.Nanosecond)
+	if es.Len() != 50 {
+		t.Fatalf("len = %d, want 50", es.Len())
+	}
+
+	time.Sleep(time.Millisecond)
+	if es.Size() != 49 || es.Len() != 49 {
+		t.Fatalf("len = %d after deleting the expired, want 49", es.Len())
+	}
+	es.Clear()
+	if es.Len() != 0 {
+		t.Fatalf("len = %d after clear, want 0", es.Len())
+	}
+}
+
+
+func TestExpireOnRead(t *testing.T) {
+	var expired []interface{}
+	es := NewSet[int](WithExpireOnRead(), WithOnExpire(func(elem interface{}) {
+		expired = append(expired, elem)
+	}))
+	es.AddWithExpire(1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if es.Contains(1) || es.Len() != 0 || len(expired) != 1 {
+		t.Fatalf("len = %d and expired %v after the read, want 0 and [1]", es.Len(), expired)
+	}
+
+	lazy := NewSet[int]()
+	lazy.AddWithExpire(1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if lazy.Contains(1) || lazy.Len() != 1 {
+		t.Fatalf("len = %d without expire on read, want 1", lazy.Len())
+	}
+}
+
+
+func TestUnsafeSet(t *testing.T) {
+	es := NewUnsafeSet[int](WithMaxSize(10))
+	for i := 0; i < 20; i++ {
+		es.Add(i)
+	}
+	es.AddWithExpire(100, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if es.Size() != 9 || es.Contains(100) {
+		t.Fatalf("size = %d, want 9", es.Size())
+	}
+	if !es.Intersect(es).unsync {
+		t.Fatal("derived set isn't unsafe")
+	}
+
+	// the readers don't take the lock either,
+	// so holding it doesn't block them
+	es.mutex.Lock()
+	if es.Weight() != 0 || es.Len() != 9 {
+		t.Fatalf("weight = %d, len = %d", es.Weight(), es.Len())
+	}
+	es.mutex.Unlock()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("unsafe set with a janitor doesn't panic")
+		}
+	}()
+	NewUnsafe(WithJanitor(time.Second))
+}
+
+
+func TestUnion(t *testing.T) {
+	one := setOf(1, 2, 3)
+	other := setOf(3, 4)
+	other.AddWithExpire(5, time.Hour)
+
+	union := one.Union(other)
+	if got := sortedInts(union.GetAll()); !equalInts(got, []int{1, 2, 3, 4, 5}) {
+		t.Fatalf("union = %v, want [1 2 3 4 5]", got)
+	}
+	if one.Size() != 3 || other.Size() != 3 {
+		t.Fatalf("union changed the sets to %v and %v", one.GetAll(), other.GetAll())
+	}
+	if _, ok := union.TTL(5); !ok {
+		t.Fatal("union lost the ttl")
+	}
+
+	union.Add(9)
+	union.Expire(1, time.Minute)
+	if one.Contains(9) || other.Contains(9) {
+		t.Fatal("adding to the union changed the sets")
+	}
+	if _, ok := one.TTL(1); ok {
+		t.Fatal("changing the ttl in the union changed the set")
+	}
+
+	clone := one.Clone()
+	clone.Add(9)
+	if one.Contains(9) {
+		t.Fatal("adding to the clone changed the set")
+	}
+}
+
+
+func TestDifference(t *testing.T) {
+	one := setOf(1, 2, 3)
+	other := setOf(3, 4)
+
+	if got := sortedInts(one.Difference(other).GetAll()); !equalInts(got, []int{1, 2}) {
+		t.Fatalf("one \\ other = %v, want [1 2]", got)
+	}
+	if got := other.Difference(one).GetAll(); !equalInts(got, []int{4}) {
+		t.Fatalf("other \\ one = %v, want [4]", got)
+	}
+	if one.Difference(one).Size() != 0 {
+		t.Fatal("set minus itself isn't empty")
+	}
+	if one.Size() != 3 || other.Size() != 2 {
+		t.Fatal("difference changed the sets")
+	}
+}
+
+
+func TestSymmetricDifference(t *testing.T) {
+	one := setOf(1, 2, 3)
+	other := setOf(3, 4)
+	other.AddWithExpire(5, time.Hour)
+
+	diff := one.SymmetricDifference(other)
+	if got := sortedInts(diff.GetAll()); !equalInts(got, []int{1, 2, 4, 5}) {
+		t.Fatalf("symmetric difference = %v, want [1 2 4 5]", got)
+	}
+	if _, ok := diff.TTL(5); !ok {
+		t.Fatal("symmetric difference lost the ttl")
+	}
+	if one.Size() != 3 || other.Size() != 3 {
+		t.Fatal("symmetric difference changed the sets")
+	}
+}
+
+
+func TestInPlaceOperations(t *testing.T) {
+	es := setOf(1, 2, 3, 4)
+	other := setOf(3, 4, 5)
+
+	es.IntersectWith(other)
+	if got := sortedInts(es.GetAll()); !equalInts(got, []int{3, 4}) || es.Len() != 2 {
+		t.Fatalf("IntersectWith left %v, want [3 4]", got)
+	}
+
+	es.AddAll(1, 2)
+	es.DifferenceWith(other)
+	if got := sortedInts(es.GetAll()); !equalInts(got, []int{1, 2}) {
+		t.Fatalf("DifferenceWith left %v, want [1 2]", got)
+	}
+
+	es.UnionWith(other)
+	if got := sortedInts(es.GetAll()); !equalInts(got, []int{1, 2, 3, 4, 5}) {
+		t.Fatalf("UnionWith left %v, want [1 2 3 4 5]", got)
+	}
+	es.UnionWith(es)
+
+	merged := setOf(1)
+	merged.Merge(other)
+	if got := sortedInts(merged.GetAll()); !equalInts(got, []int{1, 3, 4, 5}) {
+		t.Fatalf("Merge left %v, want [1 3 4 5]", got)
+	}
+
+	if n := es.RetainAll(1, 9); n != 4 || !equalInts(es.GetAll(), []int{1}) {
+		t.Fatalf("RetainAll removed %d and left %v, want 4 and [1]", n, es.GetAll())
+	}
+	if other.Size() != 3 {
+		t.Fatal("in place operations changed the other set")
+	}
+
+	other.DifferenceWith(other)
+	if other.Size() != 0 {
+		t.Fatal("set minus itself isn't empty")
+	}
+}
+
+
+func TestUnionAll(t *testing.T) {
+	one := setOf(1, 2, 3)
+	two := setOf(3, 4)
+	three := setOf(3, 2, 9)
+
+	if got := sortedInts(UnionAll(one, two, three).GetAll()); !equalInts(got, []int{1, 2, 3, 4, 9}) {
+		t.Fatalf("UnionAll = %v, want [1 2 3 4 9]", got)
+	}
+	if got := IntersectAll(one, two, three).GetAll(); !equalInts(got, []int{3}) {
+		t.Fatalf("IntersectAll = %v, want [3]", got)
+	}
+	if UnionAll[int]().Size() != 0 || IntersectAll[int]().Size() != 0 {
+		t.Fatal("operations on no sets aren't empty")
+	}
+	if IntersectAll(one, one).Size() != 3 || one.Size() != 3 {
+		t.Fatal("IntersectAll of a set with itself isn't the set")
+	}
+}
+
+
+func TestDisjoint(t *testing.T) {
+	one := setOf(1, 2)
+	other := setOf(3)
+	other.AddWithExpire(2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if !one.Disjoint(other) || !other.Disjoint(one) {
+		t.Fatal("sets sharing only an expired element aren't disjoint")
+	}
+	other.Add(1)
+	if one.Disjoint(other) || one.Disjoint(one) {
+		t.Fatal("sets sharing an element are disjoint")
+	}
+}
+
+
+func TestSubsets(t *testing.T) {
+	sub := setOf(1, 2)
+	sub.AddWithExpire(7, time.Nanosecond)
+	sub.AddWithExpire(8, time.Nanosecond)
+	super := setOf(1, 2, 3)
+	time.Sleep(time.Millisecond)
+
+	if !sub.IsSubSet(super) || !super.IsSuperSet(sub) {
+		t.Fatal("expired elements count for the subset relation")
+	}
+	if !sub.IsProperSubset(super) || super.IsProperSubset(sub) || sub.IsProperSubset(sub) {
+		t.Fatal("wrong proper subset relation")
+	}
+
+	super.Remove(3)
+	if sub.IsProperSubset(super) || !sub.IsSubSet(super) {
+		t.Fatal("equal sets are proper subsets")
+	}
+}
+
+
+func TestPop(t *testing.T) {
+	es := setOf(1, 2)
+	es.AddWithExpire(3, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	x, ok1 := es.Pop()
+	y, ok2 := es.Pop()
+	_, ok3 := es.Pop()
+	if !ok1 || !ok2 || ok3 || x + y != 3 {
+		t.Fatalf("popped %d, %d, want 1 and 2 and then nothing", x, y)
+	}
+	if es.Size() != 0 {
+		t.Fatalf("has %v after popping everything", es.GetAll())
+	}
+}
+
+
+func TestRandom(t *testing.T) {
+	es := setOf(1, 2, 3)
+
+	if got := es.Random(2); len(got) != 2 || got[0] == got[1] {
+		t.Fatalf("Random(2) = %v, want 2 different elements", got)
+	}
+	if got := sortedInts(es.Random(10)); !equalInts(got, []int{1, 2, 3}) {
+		t.Fatalf("Random(10) = %v, want all of them", got)
+	}
+	if es.Random(0) != nil || es.Size() != 3 {
+		t.Fatal("Random(0) isn't nil or Random removed elements")
+	}
+}
+
+
+// Returns a set of the elems, which never expire.
+func setOf[T comparable](elems ...T) *Set[T] {
+	es := NewSet[T]()
+	for _, elem := range elems {
+		es.Add(elem)
+	}
+
+	return es
+}
+
+
+func sorted(elems []string) []string {
+	sort.Strings(elems)
+	return elems
+}
+
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+
+func sortedInts(elems []int) []int {
+	sort.Ints(elems)
+	return elems
+}
+
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,195 @@
+package eset
+
+import "sync"
+
+// EvictionPolicy decides which element is evicted
+// when a set with a max size is full.
+type EvictionPolicy int
+
+const (
+	// Evicts the least recently used element.
+	LRU EvictionPolicy = iota
+	// Evicts the least frequently used element,
+	// the frequencies decay over time so that
+	// elements that were hot long ago can be evicted.
+	LFU
+	// Adaptive replacement cache, balances between
+	// recency and frequency based on the workload.
+	ARC
+	// Evicts the earliest added element regardless of accesses,
+	// adding an element that is already in the set doesn't renew it.
+	FIFO
+)
+
+// evictor keeps track of the elements for an eviction policy.
+// It has its own lock, so that accesses can be recorded
+// while only holding the read lock of the set.
+type evictor[T comparable] interface {
+	// Records an element that is added or replaced.
+	add(key T)
+	// Records an access to an element.
+	access(key T)
+	remove(key T)
+	// Returns the element that should be evicted.
+	victim() (T, bool)
+}
+
+
+func(es *Set[T]) newEvictor() evictor[T] {
+	if es.maxSize <= 0 && es.maxWeight <= 0 {
+		return nil
+	}
+
+	var policy evictor[T]
+	switch es.policy {
+	case LFU:
+		policy = newLFU[T]()
+	case ARC:
+		policy = newARC[T](es.maxSize)
+	case FIFO:
+		policy = newFIFO[T]()
+	default:
+		policy = newLRU[T]()
+	}
+
+	if es.tinyLFU {
+		size := es.maxSize
+		if size <= 0 {
+			size = 1024
+		}
+		policy = newTinyLFU(policy, size)
+	}
+
+	return policy
+}
+
+
+// Evicts elements until the set is no longer
+// over its max size or max weight.
+// If the candidate has just been added,
+// the admission filter may decide to evict it instead.
+func(es *Set[T]) evict(candidate T, isNew bool) {
+	for es.isFull() {
+		key, ok := es.evictor.victim()
+		if !ok {
+			return
+		}
+
+		if isNew && key != candidate {
+			isNew = false
+			if filter, ok := es.evictor.(admitter[T]); ok && !filter.admit(candidate, key) {
+				key = candidate
+			}
+		}
+
+		es.pend(es.elemOf(key, es.elems[key]), Evicted)
+		// the evicted elements stay in the store
+		es.unmirrored = es.store != nil
+		es.del(key)
+		es.unmirrored = false
+	}
+}
+
+
+func(es *Set[T]) isFull() bool {
+	return es.maxSize > 0 && len(es.elems) > es.maxSize ||
+		es.maxWeight > 0 && es.totalWeight > es.maxWeight
+}
+
+
+// Records the weight of the element stored under the key.
+func(es *Set[T]) weigh(key T, b base) {
+	weight := es.weigher(es.elemOf(key, b))
+	es.totalWeight += weight - es.weights[key]
+	es.weights[key] = weight
+}
+
+
+// Returns the total weight of the elements in the set,
+// it's always 0 unless the set has a weigher.
+func(es *Set[T]) Weight() int64 {
+	es.rlock()
+	defer es.runlock()
+	return es.totalWeight
+}
+
+
+type lru[T comparable] struct {
+	mutex sync.Mutex
+	list  *linkedList[T]
+	nodes map[T]*node[T]
+}
+
+
+func newLRU[T comparable]() *lru[T] {
+	return &lru[T]{
+		list:  newLinkedList[T](),
+		nodes: make(map[T]*node[T]),
+	}
+}
+
+
+func(l *lru[T]) add(key T) {
+	l.mutex.Lock()
+	if n, isExist := l.nodes[key]; isExist {
+		l.list.moveToFront(n)
+	} else {
+		l.nodes[key] = l.list.pushFront(key)
+	}
+	l.mutex.Unlock()
+}
+
+
+func(l *lru[T]) access(key T) {
+	l.mutex.Lock()
+	if n, isExist := l.nodes[key]; isExist {
+		l.list.moveToFront(n)
+	}
+	l.mutex.Unlock()
+}
+
+
+func(l *lru[T]) remove(key T) {
+	l.mutex.Lock()
+	if n, isExist := l.nodes[key]; isExist {
+		l.list.remove(n)
+		delete(l.nodes, key)
+	}
+	l.mutex.Unlock()
+}
+
+
+func(l *lru[T]) victim() (key T, ok bool) {
+	l.mutex.Lock()
+	if n := l.list.back(); n != nil {
+		key, ok = n.key, true
+	}
+	l.mutex.Unlock()
+	return key, ok
+}
+
+
+// fifo is an lru that ignores accesses and replacements.
+type fifo[T comparable] struct {
+	lru[T]
+}
+
+
+func newFIFO[T comparable]() *fifo[T] {
+	return &fifo[T]{lru[T]{
+		list:  newLinkedList[T](),
+		nodes: make(map[T]*node[T]),
+	}}
+}
+
+
+func(f *fifo[T]) add(key T) {
+	f.mutex.Lock()
+	if _, isExist := f.nodes[key]; !isExist {
+		f.nodes[key] = f.list.pushFront(key)
+	}
+	f.mutex.Unlock()
+}
+
+
+func(f *fifo[T]) access(key T) {}
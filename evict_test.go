@@ -0,0 +1,311 @@
+package eset
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// evictionCase runs ops on a set of at most 3 elements created with opts.
+type evictionCase struct {
+	name string
+	opts []Option
+	ops  func(es *Set[int])
+	want []int
+}
+
+
+func runEvictionCases(t *testing.T, tests []evictionCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := NewSet[int](append([]Option{WithMaxSize(3)}, tt.opts...)...)
+			tt.ops(es)
+			if got := sortedInts(es.GetAll()); !equalInts(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+
+func TestLRU(t *testing.T) {
+	runEvictionCases(t, []evictionCase{
+		{
+			name: "evicts the least recently used",
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Add(2)
+				es.Add(3)
+				es.Contains(1)
+				es.Add(4)
+			},
+			want: []int{1, 3, 4},
+		},
+		{
+			name: "renews the added again",
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Add(2)
+				es.Add(3)
+				es.Add(1)
+				es.Add(4)
+			},
+			want: []int{1, 3, 4},
+		},
+		{
+			name: "forgets the removed",
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Add(2)
+				es.Add(3)
+				es.Remove(1)
+				es.Add(4)
+				es.Add(5)
+			},
+			want: []int{3, 4, 5},
+		},
+	})
+}
+
+
+func TestLFU(t *testing.T) {
+	runEvictionCases(t, []evictionCase{
+		{
+			name: "evicts the least frequently used",
+			opts: []Option{WithEvictionPolicy(LFU)},
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Contains(1)
+				es.Add(2)
+				es.Add(3)
+				// LRU would evict 1
+				es.Add(4)
+			},
+			want: []int{1, 3, 4},
+		},
+		{
+			name: "keeps the often used",
+			opts: []Option{WithEvictionPolicy(LFU)},
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Add(2)
+				es.Add(3)
+				for i := 0; i < 5; i++ {
+					es.Contains(1)
+					es.Contains(3)
+				}
+				es.Add(4)
+				es.Add(5)
+			},
+			want: []int{1, 3, 5},
+		},
+	})
+}
+
+
+func TestEvictionByWeight(t *testing.T) {
+	weigher := func(elem interface{}) int64 {
+		return int64(len(elem.(string)))
+	}
+
+	tests := []struct {
+		name   string
+		add    []string
+		want   []string
+		weight int64
+	}{
+		{"within the limit", []string{"aa", "bbb", "ccccc"}, []string{"aa", "bbb", "ccccc"}, 10},
+		{"evicts until it fits", []string{"aa", "bbb", "ccccc", "dddd"}, []string{"ccccc", "dddd"}, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := NewSet[string](WithMaxWeight(10), WithWeigher(weigher))
+			for _, elem := range tt.add {
+				es.Add(elem)
+			}
+			if got := sorted(es.GetAll()); !equalStrings(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			if es.Weight() != tt.weight {
+				t.Fatalf("weight = %d, want %d", es.Weight(), tt.weight)
+			}
+		})
+	}
+
+	es := NewSet[string](WithMaxWeight(10), WithWeigher(weigher))
+	es.Add("aaaa")
+	es.Add("bb")
+	es.Remove("aaaa")
+	if es.Weight() != 2 {
+		t.Fatalf("weight = %d after remove, want 2", es.Weight())
+	}
+}
+
+
+func TestTinyLFU(t *testing.T) {
+	runEvictionCases(t, []evictionCase{
+		{
+			name: "rejects the rarely seen",
+			opts: []Option{WithTinyLFU()},
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Add(2)
+				es.Add(3)
+				for i := 0; i < 3; i++ {
+					es.Contains(1)
+					es.Contains(2)
+					es.Contains(3)
+				}
+				es.Add(4)
+			},
+			want: []int{1, 2, 3},
+		},
+		{
+			name: "admits the often seen",
+			opts: []Option{WithTinyLFU()},
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Add(2)
+				es.Add(3)
+				es.Contains(2)
+				es.Contains(3)
+				for i := 0; i < 3; i++ {
+					es.Contains(4)
+				}
+				es.Add(4)
+			},
+			want: []int{2, 3, 4},
+		},
+	})
+}
+
+
+func TestOnEvict(t *testing.T) {
+	got := map[EvictReason][]interface{}{}
+	es := NewSet[int](WithMaxSize(2), WithOnEvict(func(elem interface{}, reason EvictReason) {
+		got[reason] = append(got[reason], elem)
+	}))
+	es.Add(1)
+	es.Add(2)
+	es.Add(3)
+	es.Add(3)
+	es.Remove(2)
+	es.AddWithExpire(4, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	es.Size()
+
+	want := map[EvictReason][]interface{}{
+		Evicted:  {1},
+		Replaced: {3},
+		Removed:  {2},
+		Expired:  {4},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+
+func TestMaxBytes(t *testing.T) {
+	es := NewSet[string](WithMaxBytes(1000))
+	for i := 0; i < 100; i++ {
+		es.Add(fmt.Sprint("key-", i))
+	}
+
+	if es.Weight() > 1000 {
+		t.Fatalf("weight = %d, want at most 1000", es.Weight())
+	}
+	if es.Size() < 5 || es.Size() == 100 {
+		t.Fatalf("size = %d", es.Size())
+	}
+	if !es.Contains("key-99") {
+		t.Fatal("last added element is evicted")
+	}
+}
+
+
+func TestARC(t *testing.T) {
+	runEvictionCases(t, []evictionCase{
+		{
+			name: "evicts the recent before the frequent",
+			opts: []Option{WithEvictionPolicy(ARC)},
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Add(2)
+				es.Add(3)
+				es.Contains(1)
+				es.Contains(2)
+				es.Add(4)
+			},
+			want: []int{1, 2, 4},
+		},
+		{
+			name: "brings back a ghost as frequent",
+			opts: []Option{WithEvictionPolicy(ARC)},
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Add(2)
+				es.Add(3)
+				es.Contains(1)
+				es.Contains(2)
+				// 3 is evicted to the ghost list, adding it again
+				// grows the recent target and evicts the oldest frequent
+				es.Add(4)
+				es.Add(3)
+			},
+			want: []int{2, 3, 4},
+		},
+	})
+
+	es := NewSet[int](WithMaxSize(10), WithEvictionPolicy(ARC))
+	for i := 0; i < 5; i++ {
+		es.Add(i)
+		es.Contains(i)
+	}
+	for i := 100; i < 300; i++ {
+		es.Add(i)
+	}
+	for i := 0; i < 5; i++ {
+		if !es.Contains(i) {
+			t.Fatalf("scan evicted the frequent %d", i)
+		}
+	}
+
+	weighted := NewSet[int](WithMaxWeight(5), WithEvictionPolicy(ARC))
+	for i := 0; i < 50; i++ {
+		weighted.Add(i)
+	}
+	if weighted.Size() != 5 {
+		t.Fatalf("size = %d with a max weight of 5, want 5", weighted.Size())
+	}
+}
+
+
+func TestFIFO(t *testing.T) {
+	runEvictionCases(t, []evictionCase{
+		{
+			name: "ignores the accesses",
+			opts: []Option{WithEvictionPolicy(FIFO)},
+			ops: func(es *Set[int]) {
+				es.Add(1)
+				es.Add(2)
+				es.Add(3)
+				es.Contains(1)
+				es.Add(1)
+				es.Add(4)
+			},
+			want: []int{2, 3, 4},
+		},
+	})
+
+	es := NewSet[string](WithMaxWeight(10), WithEvictionPolicy(FIFO), WithWeigher(func(elem interface{}) int64 {
+		return int64(len(elem.(string)))
+	}))
+	es.Add("aa")
+	es.Add("bbbbbbbbbbbb")
+	if es.Size() != 0 || es.Weight() != 0 {
+		t.Fatalf("has %v with weight %d, want nothing", es.GetAll(), es.Weight())
+	}
+}
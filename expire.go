@@ -0,0 +1,233 @@
+package eset
+
+import "time"
+
+// expirer indexes the elements with ttl by their expiration time,
+// so that the expired elements can be found
+// without scanning the whole set.
+// Items are not removed when their elements are removed or get a new ttl,
+// instead they are skipped once popped if the base they hold
+// is no longer the one stored in the set.
+type expirer[T comparable] interface {
+	push(key T, b base)
+	// Removes at most limit items that expired before now,
+	// or all of them if limit is 0, and calls fn with them.
+	// Returns whether there are more expired items.
+	popExpired(now time.Time, limit int, fn func(item expireItem[T])) (more bool)
+	// Calls fn with the items that expire before the time.
+	due(before time.Time, fn func(item expireItem[T]))
+	// Returns the item that expires first among the valid ones.
+	first(valid func(item expireItem[T]) bool) (expireItem[T], bool)
+	len() int
+	clone() expirer[T]
+	reset()
+}
+
+type expireItem[T comparable] struct {
+	key  T
+	base base
+}
+
+
+func(es *Set[T]) newExpirer() expirer[T] {
+	if es.wheelTick > 0 {
+		return newTimingWheel[T](es.wheelTick, es.wheelSlots, es.wheelLevels)
+	}
+
+	return &expireHeap[T]{}
+}
+
+
+// Rebuilds the index from the set,
+// which drops the items of removed elements and replaced ttl.
+func(es *Set[T]) rebuildExpires() {
+	if es.expires == nil {
+		return
+	}
+
+	es.expires.reset()
+	for key, b := range es.elems {
+		if b.hasTTL() {
+			es.expires.push(key, b)
+		}
+	}
+}
+
+
+// Returns the element that expires first,
+// and the time it expires at.
+// ok is false if no element has ttl.
+func(es *Set[T]) NextExpiration() (elem T, expireAt time.Time, ok bool) {
+	es.lock()
+	es.delExpiredElems(0)
+	if es.expires == nil {
+		es.unlock()
+		return elem, expireAt, false
+	}
+
+	item, ok := es.expires.first(func(item expireItem[T]) bool {
+		return es.elems[item.key] == item.base
+	})
+	if ok {
+		elem, expireAt = es.elemOf(item.key, item.base), item.base.expireTime()
+	}
+
+	es.unlock()
+	return elem, expireAt, ok
+}
+
+
+// Deletes an expired element,
+// and quarantines it if the set has a quarantine.
+func(es *Set[T]) expire(key T, b base) {
+	es.del(key)
+	if es.quarantine {
+		es.quarantined = append(es.quarantined, Entry[T]{
+			Elem:     es.elemOf(key, b),
+			ExpireAt: b.expireTime(),
+		})
+	}
+
+	es.pend(es.elemOf(key, b), Expired)
+}
+
+
+// Returns the channel that receives the expired elements,
+// it's nil unless the set is created with WithExpiredChan.
+// The channel is never closed.
+func(es *Set[T]) Expired() <-chan T {
+	return es.expiredCh
+}
+
+
+// Returns the expired elements that have been quarantined
+// since the last call, and empties the quarantine.
+// It's always empty unless the set is created with WithExpiredQuarantine.
+func(es *Set[T]) DrainExpired() []Entry[T] {
+	es.lock()
+	quarantined := es.quarantined
+	es.quarantined = nil
+	es.unlock()
+	return quarantined
+}
+
+
+// Returns how many expired elements have been dropped
+// because the channel returned by Expired was full.
+func(es *Set[T]) ExpiredDropped() uint64 {
+	return es.expiredDropped.Load()
+}
+
+
+// expireHeap is a min-heap of the elements with ttl,
+// ordered by their expiration time.
+// It doesn't use container/heap, which boxes every item into interface{}.
+type expireHeap[T comparable] []expireItem[T]
+
+
+func(h *expireHeap[T]) push(key T, b base) {
+	*h = append(*h, expireItem[T]{key: key, base: b})
+	h.up(len(*h)-1)
+}
+
+
+// Removes and returns the item that expires first.
+func(h *expireHeap[T]) pop() expireItem[T] {
+	old := *h
+	n := len(old) - 1
+	item := old[0]
+	old[0] = old[n]
+	old[n] = expireItem[T]{}
+	*h = old[:n]
+	h.down(0)
+	return item
+}
+
+
+func(h expireHeap[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if h[parent].base.expireAt <= h[i].base.expireAt {
+			return
+		}
+		h[parent], h[i] = h[i], h[parent]
+		i = parent
+	}
+}
+
+
+func(h expireHeap[T]) down(i int) {
+	for {
+		least := i
+		for _, child := range [2]int{2*i+1, 2*i+2} {
+			if child < len(h) && h[child].base.expireAt < h[least].base.expireAt {
+				least = child
+			}
+		}
+
+		if least == i {
+			return
+		}
+		h[least], h[i] = h[i], h[least]
+		i = least
+	}
+}
+
+
+func(h *expireHeap[T]) popExpired(now time.Time, limit int, fn func(item expireItem[T])) bool {
+	for n := 0; len(*h) > 0 && (*h)[0].base.expireAt < now.UnixNano(); n++ {
+		if limit > 0 && n == limit {
+			return true
+		}
+		fn(h.pop())
+	}
+
+	return false
+}
+
+
+// The invalid items on the top are dropped.
+func(h *expireHeap[T]) first(valid func(item expireItem[T]) bool) (expireItem[T], bool) {
+	for len(*h) > 0 {
+		if valid((*h)[0]) {
+			return (*h)[0], true
+		}
+		h.pop()
+	}
+
+	return expireItem[T]{}, false
+}
+
+
+func(h *expireHeap[T]) due(before time.Time, fn func(item expireItem[T])) {
+	h.dueFrom(0, before, fn)
+}
+
+
+// Visits the subtree rooted at i,
+// skipping the subtrees whose root is not due since their items expire later.
+func(h *expireHeap[T]) dueFrom(i int, before time.Time, fn func(item expireItem[T])) {
+	if i >= len(*h) || (*h)[i].base.expireAt >= before.UnixNano() {
+		return
+	}
+
+	fn((*h)[i])
+	h.dueFrom(2*i+1, before, fn)
+	h.dueFrom(2*i+2, before, fn)
+}
+
+
+func(h *expireHeap[T]) len() int {
+	return len(*h)
+}
+
+
+func(h *expireHeap[T]) clone() expirer[T] {
+	newHeap := append(expireHeap[T](nil), *h...)
+	return &newHeap
+}
+
+
+func(h *expireHeap[T]) reset() {
+	*h = nil
+}
@@ -0,0 +1,298 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+// Returns the number of items in the expiration index, stale or not.
+func expiresLen[T comparable](es *Set[T]) int {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+	return es.expires.len()
+}
+
+
+func TestExpireHeap(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 1000; i++ {
+		es.AddWithExpire(i, time.Millisecond)
+	}
+	es.AddWithExpire(5, time.Hour)
+	es.Add(6)
+	// each one leaves a stale item in the heap
+	for i := 0; i < 1000; i++ {
+		es.AddWithExpire(7, time.Hour)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if got := sortedInts(es.GetAll()); !equalInts(got, []int{5, 6, 7}) {
+		t.Fatalf("has %v, want [5 6 7]", got)
+	}
+	// the stale items are dropped once they outnumber the elements
+	es.AddWithExpire(5, time.Hour)
+	if n := expiresLen(es); n > 200 {
+		t.Fatalf("heap has %d items for 2 elements with ttl", n)
+	}
+}
+
+
+func TestTimingWheelSet(t *testing.T) {
+	es := NewSet[int](WithTimingWheel(time.Millisecond, 8, 3))
+	for i := 0; i < 100; i++ {
+		es.AddWithExpire(i, time.Duration(i % 10 + 1) * time.Millisecond)
+	}
+	es.AddWithExpire(100, time.Hour)
+	es.Add(101)
+
+	time.Sleep(20 * time.Millisecond)
+	if got := sortedInts(es.GetAll()); !equalInts(got, []int{100, 101}) {
+		t.Fatalf("has %v, want [100 101]", got)
+	}
+	if es.Size() != 2 {
+		t.Fatalf("size = %d, want 2", es.Size())
+	}
+	if n := expiresLen(es); n != 1 {
+		t.Fatalf("wheel has %d items, want 1", n)
+	}
+}
+
+
+func TestExpireAt(t *testing.T) {
+	es := NewSet[int]()
+	es.AddWithExpireAt(1, time.Now().Add(-time.Second))
+	es.AddWithExpireAt(2, time.Now().Add(time.Hour))
+	es.Add(3)
+
+	if es.Contains(1) || !es.Contains(2) {
+		t.Fatalf("has %v, want [2 3]", es.GetAll())
+	}
+	if ttl, err := es.GetElemTTL(2); err != nil || ttl < 3599 {
+		t.Fatalf("ttl of 2 = %v, %v", ttl, err)
+	}
+
+	if !es.ExpireAt(3, time.Now().Add(time.Millisecond)) || es.ExpireAt(4, time.Now()) {
+		t.Fatal("ExpireAt doesn't report whether the element exists")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if es.Contains(3) {
+		t.Fatal("element didn't expire at the time")
+	}
+}
+
+
+func TestPersist(t *testing.T) {
+	es := NewSet[int]()
+	es.AddWithExpire(1, time.Millisecond)
+	es.Add(2)
+
+	if !es.Persist(1) || es.Persist(1) || es.Persist(2) || es.Persist(3) {
+		t.Fatal("Persist doesn't report whether the element had ttl")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if !es.Contains(1) {
+		t.Fatal("persisted element expired")
+	}
+	if _, err := es.GetElemTTL(1); err == nil {
+		t.Fatal("persisted element has ttl")
+	}
+}
+
+
+func TestExpire(t *testing.T) {
+	es := NewSet[int]()
+	es.Add(1)
+	es.AddWithExpire(2, time.Millisecond)
+
+	if !es.Expire(1, time.Hour) || !es.Expire(2, time.Hour) || es.Expire(3, time.Hour) {
+		t.Fatal("Expire doesn't report whether the element exists")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if !es.Contains(2) {
+		t.Fatal("element expired with its old ttl")
+	}
+	if ttl, err := es.GetElemTTL(1); err != nil || ttl < 3599 {
+		t.Fatalf("ttl of 1 = %v, %v, want an hour", ttl, err)
+	}
+
+	keyed := New(WithKeyFunc(func(elem interface{}) interface{} {
+		if u, ok := elem.(user); ok {
+			return u.ID
+		}
+		return elem
+	}))
+	keyed.Add(user{1, "alice"})
+	keyed.Expire(1, time.Hour)
+	keyed.Persist(1)
+	if got := keyed.GetAll(); len(got) != 1 || got[0].(user).Name != "alice" {
+		t.Fatalf("has %v, want alice", got)
+	}
+}
+
+
+func TestSlidingExpiration(t *testing.T) {
+	es := NewSet[int](WithSlidingExpiration())
+	es.AddWithExpire(1, 30 * time.Millisecond)
+	es.AddWithExpire(2, 30 * time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if !es.Contains(1) {
+			t.Fatalf("accessed element expired after %d accesses", i)
+		}
+	}
+	if es.Peek(2) {
+		t.Fatal("element that isn't accessed didn't expire")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if es.Peek(1) {
+		t.Fatal("element didn't expire after it stopped being accessed")
+	}
+}
+
+
+func TestIdleTimeoutAndMaxLifetime(t *testing.T) {
+	es := NewSet[int](WithIdleTimeout(30 * time.Millisecond), WithMaxLifetime(80 * time.Millisecond))
+	es.Add(1)
+	es.Add(2)
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+		es.Contains(1)
+	}
+	if !es.Peek(1) || es.Peek(2) {
+		t.Fatal("idle element didn't expire, or the accessed one did")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	es.Contains(1)
+	time.Sleep(20 * time.Millisecond)
+	if es.Peek(1) {
+		t.Fatal("element outlived the max lifetime")
+	}
+
+	es.AddWithExpire(3, time.Hour)
+	if ttl, ok := es.TTL(3); !ok || ttl > 80 * time.Millisecond {
+		t.Fatalf("ttl = %v, want at most the max lifetime", ttl)
+	}
+}
+
+
+func TestDefaultTTL(t *testing.T) {
+	es := NewSet[int](WithDefaultTTL(time.Hour))
+	es.Add(1)
+	es.AddWithExpire(2, time.Minute)
+
+	if ttl, ok := es.TTL(1); !ok || ttl < 59 * time.Minute {
+		t.Fatalf("ttl of 1 = %v, want the default ttl", ttl)
+	}
+	if ttl, ok := es.TTL(2); !ok || ttl > time.Minute {
+		t.Fatalf("ttl of 2 = %v, want the given ttl", ttl)
+	}
+}
+
+
+func TestTouch(t *testing.T) {
+	es := NewSet[int]()
+	es.AddWithExpire(1, 30 * time.Millisecond)
+	es.Add(2)
+
+	time.Sleep(20 * time.Millisecond)
+	if !es.Touch(1) || es.Touch(3) {
+		t.Fatal("Touch doesn't report whether the element exists")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !es.Contains(1) {
+		t.Fatal("touched element expired")
+	}
+	if _, ok := es.TTL(2); ok {
+		t.Fatal("element without ttl got one")
+	}
+}
+
+
+func TestGetExpireAt(t *testing.T) {
+	es := NewSet[int]()
+	expireAt := time.Now().Add(time.Hour)
+	es.AddWithExpireAt(1, expireAt)
+	es.Add(2)
+
+	if got, ok := es.GetExpireAt(1); !ok || !got.Equal(expireAt) {
+		t.Fatalf("GetExpireAt(1) = %v, %v, want %v", got, ok, expireAt)
+	}
+	if _, ok := es.GetExpireAt(2); ok {
+		t.Fatal("element without ttl has an expiration time")
+	}
+	if _, ok := es.GetExpireAt(3); ok {
+		t.Fatal("missing element has an expiration time")
+	}
+}
+
+
+func TestNextExpiration(t *testing.T) {
+	sets := map[string]*Set[int]{
+		"heap":  NewSet[int](),
+		"wheel": NewSet[int](WithTimingWheel(time.Millisecond, 8, 2)),
+	}
+	for name, es := range sets {
+		t.Run(name, func(t *testing.T) {
+			if _, _, ok := es.NextExpiration(); ok {
+				t.Fatal("empty set has a next expiration")
+			}
+
+			es.AddWithExpire(1, time.Hour)
+			es.AddWithExpire(2, time.Minute)
+			es.AddWithExpire(3, time.Second)
+			es.Remove(3)
+			es.Add(4)
+
+			elem, expireAt, ok := es.NextExpiration()
+			if !ok || elem != 2 || time.Until(expireAt) > time.Minute {
+				t.Fatalf("next expiration = %d at %v, want 2 in a minute", elem, expireAt)
+			}
+		})
+	}
+}
+
+
+func TestBatchExpiration(t *testing.T) {
+	sets := map[string]*Set[int]{
+		"heap":  NewSet[int](),
+		"wheel": NewSet[int](WithTimingWheel(time.Millisecond, 4, 2)),
+	}
+	for name, es := range sets {
+		t.Run(name, func(t *testing.T) {
+			// more than a batch
+			for i := 0; i < 5500; i++ {
+				es.AddWithExpire(i, time.Millisecond)
+			}
+			es.Add(-1)
+
+			time.Sleep(5 * time.Millisecond)
+			if n := es.Size(); n != 1 {
+				t.Fatalf("size = %d, want 1", n)
+			}
+			if n := mapLen(es); n != 1 {
+				t.Fatalf("%d elements left in the map, want 1", n)
+			}
+		})
+	}
+}
+
+
+func TestExpiredQuarantine(t *testing.T) {
+	es := NewSet[int](WithExpiredQuarantine())
+	es.AddWithExpire(1, time.Millisecond)
+	es.Add(2)
+	time.Sleep(5 * time.Millisecond)
+	es.GetAll()
+
+	drained := es.DrainExpired()
+	if len(drained) != 1 || drained[0].Elem != 1 || drained[0].ExpireAt.IsZero() {
+		t.Fatalf("drained %v, want 1 with its expiration time", drained)
+	}
+	if len(es.DrainExpired()) != 0 {
+		t.Fatal("quarantine isn't emptied")
+	}
+}
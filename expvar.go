@@ -0,0 +1,56 @@
+package eset
+
+import "expvar"
+
+// expvarMetrics is how a set shows up on /debug/vars.
+type expvarMetrics struct {
+	Size                int     `json:"size"`
+	MaxSize             int     `json:"maxSize"`
+	Adds                uint64  `json:"adds"`
+	Removes             uint64  `json:"removes"`
+	Hits                uint64  `json:"hits"`
+	Misses              uint64  `json:"misses"`
+	HitRatio            float64 `json:"hitRatio"`
+	Expirations         uint64  `json:"expirations"`
+	Evictions           uint64  `json:"evictions"`
+	Cleanups            uint64  `json:"cleanups"`
+	CleanupPauseSeconds float64 `json:"cleanupPauseSeconds"`
+}
+
+
+// Publishes the metrics of the set under name with expvar,
+// so that they show up on /debug/vars, see Len, MaxSize and Stats.
+// They are read each time the vars are, and the counters of Stats
+// are all zero unless the set is created with WithStats.
+// Like expvar.Publish, it panics if the name is already used.
+func(es *Set[T]) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return newExpvarMetrics(es.Len(), es.MaxSize(), es.Stats())
+	}))
+}
+
+
+// Publishes the metrics of the set under name with expvar,
+// see Set.PublishExpvar.
+func(s *ShardedSet[T]) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return newExpvarMetrics(s.Len(), s.MaxSize(), s.Stats())
+	}))
+}
+
+
+func newExpvarMetrics(size, maxSize int, stats Stats) expvarMetrics {
+	return expvarMetrics{
+		Size:                size,
+		MaxSize:             maxSize,
+		Adds:                stats.Adds,
+		Removes:             stats.Removes,
+		Hits:                stats.Hits,
+		Misses:              stats.Misses,
+		HitRatio:            stats.HitRatio(),
+		Expirations:         stats.Expirations,
+		Evictions:           stats.Evictions,
+		Cleanups:            stats.Cleanups,
+		CleanupPauseSeconds: stats.CleanupPause.Seconds(),
+	}
+}
@@ -0,0 +1,32 @@
+package eset
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	es := NewSet[string](WithStats())
+	es.PublishExpvar("eset_test_set")
+	es.Add("a")
+	es.Contains("a")
+
+	var metrics map[string]interface{}
+	if err := json.Unmarshal([]byte(expvar.Get("eset_test_set").String()), &metrics); err != nil {
+		t.Fatal(err)
+	}
+	if metrics["size"] != 1.0 || metrics["hits"] != 1.0 || metrics["hitRatio"] != 1.0 {
+		t.Fatalf("published %v", metrics)
+	}
+
+	sharded := NewSharded[int](WithShards(4))
+	sharded.PublishExpvar("eset_test_sharded")
+	sharded.Add(1)
+	if err := json.Unmarshal([]byte(expvar.Get("eset_test_sharded").String()), &metrics); err != nil {
+		t.Fatal(err)
+	}
+	if metrics["size"] != 1.0 {
+		t.Fatalf("published %v", metrics)
+	}
+}
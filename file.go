@@ -0,0 +1,309 @@
+package eset
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// the header of the snapshot files written by SaveFile,
+// followed by the version of the file format
+const snapshotMagic = "ESET"
+
+// the version of the snapshot files,
+// the payload of the compressed ones is compressed by WithCompression,
+// and the one of the encrypted ones is sealed by WithSnapshotKey
+// with the header as additional data, after it's compressed if it's both
+const (
+	snapshotVersion                    = 1
+	compressedSnapshotVersion          = 2
+	encryptedSnapshotVersion           = 3
+	compressedEncryptedSnapshotVersion = 4
+)
+
+
+// Writes the unexpired elements to a snapshot file,
+// so that the set survives restarts of the process.
+// The file starts with a magic header and the version of the format,
+// followed by the layout of MarshalBinary,
+// which is compressed if the set is created with WithCompression,
+// and encrypted if it's created with WithSnapshotKey.
+// It's written to a temporary file first and renamed to path,
+// so that a crash never leaves a partial snapshot behind.
+func(es *Set[T]) SaveFile(path string) error {
+	entries := es.Entries()
+	return writeFile(path, func(w io.Writer) error {
+		return es.writeSnapshot(w, entries)
+	})
+}
+
+
+// Writes a file by write to a temporary file first,
+// which is renamed to path once it's synced to disk.
+func writeFile(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path) + ".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+
+// Writes a snapshot of the entries to w, which is the content of the snapshot files.
+func(es *Set[T]) writeSnapshot(w io.Writer, entries []Entry[T]) error {
+	data, err := marshalEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	header := append([]byte(snapshotMagic), es.snapshotVersion())
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	return es.writePayload(w, data, header)
+}
+
+
+// Returns the version of the snapshot files written by the set.
+func(es *Set[T]) snapshotVersion() byte {
+	switch {
+	case es.compress != nil && es.aead != nil:
+		return compressedEncryptedSnapshotVersion
+	case es.aead != nil:
+		return encryptedSnapshotVersion
+	case es.compress != nil:
+		return compressedSnapshotVersion
+	}
+
+	return snapshotVersion
+}
+
+
+// Writes the payload of a snapshot to w,
+// compressed and encrypted as the version in the header says.
+func(es *Set[T]) writePayload(w io.Writer, data, header []byte) error {
+	if es.aead == nil {
+		return es.writeCompressed(w, data)
+	}
+
+	var buf bytes.Buffer
+	if err := es.writeCompressed(&buf, data); err != nil {
+		return err
+	}
+	sealed, err := seal(es.aead, buf.Bytes(), header)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(sealed)
+	return err
+}
+
+
+// Replaces the elements of the set with the ones in a snapshot file
+// written by SaveFile, the options of the set are kept.
+// The elements that expired since the snapshot was taken are skipped.
+func(es *Set[T]) LoadFile(path string) error {
+	entries, err := es.readSnapshot(path)
+	if err != nil {
+		return err
+	}
+
+	es.replaceEntries(entries)
+	return nil
+}
+
+
+// Returns the unexpired entries in a snapshot file.
+func(es *Set[T]) readSnapshot(path string) ([]Entry[T], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.decodeSnapshot(data)
+}
+
+
+// Returns the unexpired entries in the content of a snapshot file.
+func(es *Set[T]) decodeSnapshot(data []byte) ([]Entry[T], error) {
+	var err error
+	header := len(snapshotMagic) + 1
+	if len(data) < header || !bytes.Equal(data[:len(snapshotMagic)], []byte(snapshotMagic)) {
+		return nil, ErrSnapshotFormat
+	}
+
+	payload := data[header:]
+	version := data[len(snapshotMagic)]
+	if version < snapshotVersion || version > compressedEncryptedSnapshotVersion {
+		return nil, ErrBinaryVersion
+	}
+	if version >= encryptedSnapshotVersion {
+		if es.aead == nil {
+			return nil, ErrEncrypted
+		}
+		if payload, err = open(es.aead, payload, data[:header]); err != nil {
+			return nil, err
+		}
+	}
+	if version == compressedSnapshotVersion || version == compressedEncryptedSnapshotVersion {
+		if es.decompress == nil {
+			return nil, ErrCompressed
+		}
+		if payload, err = es.readCompressed(bytes.NewReader(payload)); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := decodeBinary[T](payload)
+	if err != nil {
+		return nil, err
+	}
+
+	now := es.now()
+	unexpired := entries[:0]
+	for _, entry := range entries {
+		if entry.ExpireAt.IsZero() || entry.ExpireAt.After(now) {
+			unexpired = append(unexpired, entry)
+		}
+	}
+
+	return unexpired, nil
+}
+
+
+func(es *Set[T]) runAutoSnapshot() {
+	ticker := time.NewTicker(es.snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			es.autoSnapshot()
+		case <-es.stop:
+			return
+		}
+	}
+}
+
+
+// Saves the set to its snapshot sink.
+// If the set has an append-only log,
+// the snapshot is taken under the write lock to know where the log is at,
+// and the log is trimmed to the changes since the previous snapshot.
+func(es *Set[T]) autoSnapshot() {
+	es.lock()
+	a := es.aof
+	var offset, size int64
+	var rewrites int
+	if a != nil && a.err == nil {
+		if info, err := a.file.Stat(); err == nil {
+			offset, size, rewrites = info.Size(), a.size, a.rewrites
+		}
+	}
+	var entries []Entry[T]
+	if offset > 0 {
+		entries = es.entriesOf(es.elems)
+	}
+	es.unlock()
+
+	if offset == 0 {
+		entries = es.Entries()
+	}
+	err := es.snapshotSink.Save(func(w io.Writer) error {
+		return es.writeSnapshot(w, entries)
+	})
+	es.snapshotErr.Store(&err)
+	if err == nil && offset > 0 {
+		es.lock()
+		if es.aof == a {
+			es.trimAOF(offset, size, rewrites)
+		}
+		es.unlock()
+	}
+}
+
+
+// Restores the set after a crash from a snapshot written by SaveFile
+// or WithAutoSnapshot and the append-only log written since,
+// the elements of the set are replaced.
+// If the snapshot can't be read, e.g. it's corrupted,
+// the previous one of the auto snapshot at snapshotPath.prev is used instead,
+// the log of a set with both of them has the changes since the previous one.
+// The records of the log are verified by their checksums,
+// and the ones torn by the crash are dropped, see ReplayAOF.
+// It's fine if the snapshot or the log doesn't exist.
+// Call EnableAOF after it to keep logging the changes.
+func(es *Set[T]) Recover(snapshotPath, aofPath string) error {
+	entries, err := es.readLatestSnapshot(snapshotPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	es.replaceEntries(entries)
+	return es.ReplayAOF(aofPath)
+}
+
+
+// Returns the unexpired entries in a snapshot file,
+// or in the previous snapshot if it can't be read.
+func(es *Set[T]) readLatestSnapshot(path string) ([]Entry[T], error) {
+	entries, err := es.readSnapshot(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		if prevEntries, prevErr := es.readSnapshot(path + ".prev"); prevErr == nil {
+			return prevEntries, nil
+		}
+	}
+
+	return entries, err
+}
+
+
+// Adds the elements of the latest snapshot in the sink to the set,
+// or of the previous snapshot file if the latest one can't be read.
+func(es *Set[T]) restoreSnapshot() {
+	var entries []Entry[T]
+	var err error
+	if sink, ok := es.snapshotSink.(*fileSink); ok {
+		entries, err = es.readLatestSnapshot(sink.path)
+	} else {
+		entries, err = es.readSink()
+	}
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			es.snapshotErr.Store(&err)
+		}
+		return
+	}
+
+	es.addEntries(entries)
+}
+
+
+// Returns the error of the last auto snapshot,
+// or of restoring the set from it, nil if there is none.
+func(es *Set[T]) SnapshotErr() error {
+	if err := es.snapshotErr.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package eset
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.snapshot")
+	es := NewSet[string]()
+	es.Add("a")
+	es.AddWithExpire("b", time.Hour)
+	es.AddWithExpire("c", 20 * time.Millisecond)
+	if err := es.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// c expires after the snapshot is taken
+	time.Sleep(30 * time.Millisecond)
+	loaded := NewSet[string]()
+	loaded.Add("d")
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := sorted(loaded.GetAll()); !equalStrings(got, []string{"a", "b"}) {
+		t.Fatalf("loaded %v, want [a b]", got)
+	}
+	if _, ok := loaded.TTL("b"); !ok {
+		t.Fatal("b lost its ttl")
+	}
+
+	if err := os.WriteFile(path, []byte("not a snapshot"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.LoadFile(path); !errors.Is(err, ErrSnapshotFormat) {
+		t.Fatalf("error = %v, want ErrSnapshotFormat", err)
+	}
+	if err := loaded.LoadFile(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error = %v, want os.ErrNotExist", err)
+	}
+	if loaded.Size() != 2 {
+		t.Fatal("failed load changed the set")
+	}
+}
+
+
+func TestLoadFilePaused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.snapshot")
+	es := NewSet[string]()
+	es.AddWithExpire("a", 20 * time.Millisecond)
+	if err := es.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// a isn't expired by the clock of the loading set
+	loaded := NewSet[string]()
+	loaded.PauseExpiration()
+	time.Sleep(30 * time.Millisecond)
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Contains("a") {
+		t.Fatalf("loaded %v, want [a]", loaded.GetAll())
+	}
+}
+
+
+func TestAutoSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.snapshot")
+	es := NewSet[string](WithAutoSnapshot(path, 5 * time.Millisecond))
+	es.Add("a")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no snapshot is saved in the background")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Close saves the last changes
+	es.AddWithExpire("b", time.Hour)
+	if err := es.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := es.SnapshotErr(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewSet[string](WithAutoSnapshot(path, time.Hour))
+	defer restored.Close()
+	if got := sorted(restored.GetAll()); !equalStrings(got, []string{"a", "b"}) {
+		t.Fatalf("restored %v, want [a b]", got)
+	}
+	if _, ok := restored.TTL("b"); !ok {
+		t.Fatal("b lost its ttl")
+	}
+}
+
+
+func TestAutoSnapshotUnsafe(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("unsafe set with an auto snapshot doesn't panic")
+		}
+	}()
+	NewUnsafe(WithAutoSnapshot(filepath.Join(t.TempDir(), "set.snapshot"), time.Second))
+}
+
+
+func TestRecover(t *testing.T) {
+	dir := t.TempDir()
+	snapshotPath, aofPath := filepath.Join(dir, "set.snapshot"), filepath.Join(dir, "set.aof")
+	es := NewSet[string](WithAutoSnapshot(snapshotPath, time.Hour))
+	if err := es.EnableAOF(aofPath, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	es.Add("a")
+	es.Add("b")
+	es.autoSnapshot()
+	es.Remove("a")
+	es.Add("c")
+	es.autoSnapshot()
+	// the changes since the last snapshot are only in the log
+	es.Add("d")
+	es.Remove("b")
+
+	recovered := NewSet[string]()
+	if err := recovered.Recover(snapshotPath, aofPath); err != nil {
+		t.Fatal(err)
+	}
+	if got := sorted(recovered.GetAll()); !equalStrings(got, []string{"c", "d"}) {
+		t.Fatalf("recovered %v, want [c d]", got)
+	}
+
+	// a corrupted snapshot falls back to the previous one,
+	// the log still has the changes since it
+	if err := os.WriteFile(snapshotPath, []byte(snapshotMagic + "\x01garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	recovered = NewSet[string]()
+	if err := recovered.Recover(snapshotPath, aofPath); err != nil {
+		t.Fatal(err)
+	}
+	if got := sorted(recovered.GetAll()); !equalStrings(got, []string{"c", "d"}) {
+		t.Fatalf("recovered %v from the previous snapshot, want [c d]", got)
+	}
+	es.Close()
+
+	empty := NewSet[string]()
+	if err := empty.Recover(filepath.Join(dir, "missing"), filepath.Join(dir, "missing.aof")); err != nil || empty.Size() != 0 {
+		t.Fatalf("recovered %v, %v from nothing", empty.GetAll(), err)
+	}
+}
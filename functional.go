@@ -0,0 +1,158 @@
+package eset
+
+// Returns a new set that has the unexpired elements
+// for which the predicate returns true,
+// e.g. to carve the sessions of a tenant off a shared set.
+// The expiration time of the elements is kept,
+// and the new set is independent from the set.
+func(es *Set[T]) Filter(pred func(T) bool) *Set[T] {
+	newEs := es.derive()
+
+	elems, done := es.view()
+	for key, base := range elems {
+		if !es.isExpired(base) && pred(es.elemOf(key, base)) {
+			newEs.put(key, base)
+		}
+	}
+
+	done()
+	return newEs
+}
+
+
+// Splits the unexpired elements into two new sets in one pass,
+// match has the ones for which the predicate returns true,
+// and rest has the others.
+// The expiration time of the elements is kept.
+func(es *Set[T]) Partition(pred func(T) bool) (match, rest *Set[T]) {
+	match, rest = es.derive(), es.derive()
+
+	elems, done := es.view()
+	for key, base := range elems {
+		if es.isExpired(base) {
+			continue
+		}
+
+		if pred(es.elemOf(key, base)) {
+			match.put(key, base)
+		} else {
+			rest.put(key, base)
+		}
+	}
+
+	done()
+	return match, rest
+}
+
+
+// Returns a new set that has the result of fn
+// for each unexpired element in the set.
+// The expiration time of the elements is kept,
+// when several elements map to the same one, the later expiration time is kept.
+// opts are applied to the new set.
+func MapTo[T, U comparable](es *Set[T], fn func(T) U, opts ...Option) *Set[U] {
+	newEs := newSet[U](0, opts)
+	mapInto(es, newEs, fn)
+	newEs.start()
+	return newEs
+}
+
+
+// Same as MapTo, but the new set has the same element type
+// and is configured like the set,
+// e.g. to normalize the case of string IDs.
+func(es *Set[T]) Map(fn func(T) T) *Set[T] {
+	newEs := es.derive()
+	mapInto(es, newEs, fn)
+	return newEs
+}
+
+
+func mapInto[T, U comparable](es *Set[T], newEs *Set[U], fn func(T) U) {
+	elems, done := es.view()
+	for key, b := range elems {
+		if es.isExpired(b) {
+			continue
+		}
+
+		var newBase base
+		if b.hasTTL() {
+			newBase = base{expireAt: b.expireAt, ttl: b.ttl}
+		}
+
+		newElem := fn(es.elemOf(key, b))
+		old, isExist := newEs.elems[newEs.keyOf(newElem)]
+		if isExist && (!old.hasTTL() || newBase.hasTTL() && old.expireAt >= newBase.expireAt) {
+			continue
+		}
+		newEs.add(newElem, newBase)
+	}
+
+	done()
+}
+
+
+// Folds the unexpired elements in the set into a single value,
+// fn is called with the accumulated value and each element.
+// It's a single pass under the read lock,
+// so fn must not call the methods of the set that take the write lock.
+func Reduce[T comparable, A any](es *Set[T], init A, fn func(acc A, elem T) A) A {
+	acc := init
+
+	elems, done := es.view()
+	for key, base := range elems {
+		if !es.isExpired(base) {
+			acc = fn(acc, es.elemOf(key, base))
+		}
+	}
+
+	done()
+	return acc
+}
+
+
+// Removes the unexpired elements for which the predicate returns true,
+// holding the lock once, so that no writer can sneak in between.
+// The expired elements it comes across are deleted as well.
+// Returns how many elements were removed.
+func(es *Set[T]) RemoveIf(pred func(T) bool) int {
+	removed := 0
+	es.lock()
+	for key, base := range es.elems {
+		if es.isExpired(base) {
+			es.expire(key, base)
+		} else if pred(es.elemOf(key, base)) {
+			es.del(key)
+			es.pend(es.elemOf(key, base), Removed)
+			removed++
+		}
+	}
+	es.unlock()
+	return removed
+}
+
+
+// Buckets the unexpired elements by the key fn returns for them,
+// each bucket is a new set configured like the set.
+// The expiration time of the elements is kept.
+func GroupBy[T, K comparable](es *Set[T], keyFn func(T) K) map[K]*Set[T] {
+	groups := make(map[K]*Set[T])
+
+	elems, done := es.view()
+	for key, base := range elems {
+		if es.isExpired(base) {
+			continue
+		}
+
+		groupKey := keyFn(es.elemOf(key, base))
+		group, isExist := groups[groupKey]
+		if !isExist {
+			group = es.derive()
+			groups[groupKey] = group
+		}
+		group.put(key, base)
+	}
+
+	done()
+	return groups
+}
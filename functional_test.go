@@ -0,0 +1,197 @@
+package eset
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 10; i++ {
+		es.Add(i)
+	}
+	es.AddWithExpire(10, time.Hour)
+	es.AddWithExpire(12, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	even := es.Filter(func(elem int) bool {
+		return elem % 2 == 0
+	})
+	if got := sortedInts(even.GetAll()); !equalInts(got, []int{0, 2, 4, 6, 8, 10}) {
+		t.Fatalf("Filter = %v, want the unexpired even numbers", got)
+	}
+	if _, err := even.GetElemTTL(10); err != nil {
+		t.Fatal("Filter lost the ttl")
+	}
+	if es.Size() != 11 {
+		t.Fatalf("Filter changed the set to %v", es.GetAll())
+	}
+}
+
+
+func TestMapTo(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 10; i++ {
+		es.Add(i)
+	}
+
+	mods := MapTo(es, func(elem int) string {
+		return strconv.Itoa(elem % 3)
+	})
+	if got := sorted(mods.GetAll()); !equalStrings(got, []string{"0", "1", "2"}) {
+		t.Fatalf("MapTo = %v, want [0 1 2]", got)
+	}
+}
+
+
+func TestMapToOptions(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 10; i++ {
+		es.Add(i)
+	}
+
+	bounded := MapTo(es, strconv.Itoa, WithMaxSize(3))
+	if bounded.Size() != 3 {
+		t.Fatalf("MapTo with a max size of 3 has %v", bounded.GetAll())
+	}
+}
+
+
+func TestMapToCopyOnWrite(t *testing.T) {
+	es := NewSet[int]()
+	es.AddAll(1, 2, 3)
+
+	strs := MapTo(es, strconv.Itoa, WithCopyOnWrite())
+	if !strs.Contains("1") || len(strs.GetAll()) != 3 {
+		t.Fatalf("readers of the new set see %v, want [1 2 3]", strs.GetAll())
+	}
+}
+
+
+func TestReduce(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 10; i++ {
+		es.Add(i)
+	}
+	es.AddWithExpire(100, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	sum := Reduce(es, 0, func(acc, elem int) int {
+		return acc + elem
+	})
+	if sum != 45 {
+		t.Fatalf("sum = %d, want 45", sum)
+	}
+
+	longest := Reduce(setOf("a", "abc", "ab"), "", func(acc, elem string) string {
+		if len(elem) > len(acc) {
+			return elem
+		}
+		return acc
+	})
+	if longest != "abc" {
+		t.Fatalf("longest = %q, want abc", longest)
+	}
+}
+
+
+func TestRemoveIf(t *testing.T) {
+	es := NewSet[int]()
+	es.AddAll(1, 2, 3, 4, 5)
+
+	n := es.RemoveIf(func(elem int) bool {
+		return elem % 2 == 0
+	})
+	if n != 2 || es.Len() != 3 {
+		t.Fatalf("removed %d and left %d, want 2 and 3", n, es.Len())
+	}
+	if got := sortedInts(es.GetAll()); !equalInts(got, []int{1, 3, 5}) {
+		t.Fatalf("has %v, want [1 3 5]", got)
+	}
+}
+
+
+func TestFilterIndependent(t *testing.T) {
+	es := NewSet[int]()
+	es.AddAll(1, 2, 3)
+
+	filtered := es.Filter(func(elem int) bool {
+		return elem > 1
+	})
+	filtered.Add(4)
+	filtered.Remove(2)
+	filtered.Expire(3, time.Hour)
+	if got := sortedInts(es.GetAll()); !equalInts(got, []int{1, 2, 3}) {
+		t.Fatalf("changing the filtered set changed the set to %v", got)
+	}
+	if _, ok := es.TTL(3); ok {
+		t.Fatal("changing the filtered set changed the ttl in the set")
+	}
+}
+
+
+func TestPartition(t *testing.T) {
+	es := NewSet[int]()
+	es.AddAll(1, 2, 3)
+	es.AddWithExpire(4, time.Hour)
+
+	even, odd := es.Partition(func(elem int) bool {
+		return elem % 2 == 0
+	})
+	if got := sortedInts(even.GetAll()); !equalInts(got, []int{2, 4}) {
+		t.Fatalf("match = %v, want [2 4]", got)
+	}
+	if got := sortedInts(odd.GetAll()); !equalInts(got, []int{1, 3}) {
+		t.Fatalf("rest = %v, want [1 3]", got)
+	}
+	if _, ok := even.TTL(4); !ok {
+		t.Fatal("Partition lost the ttl")
+	}
+	if es.Size() != 4 {
+		t.Fatal("Partition changed the set")
+	}
+}
+
+
+func TestMap(t *testing.T) {
+	es := NewSet[string]()
+	es.AddWithExpire("A", time.Hour)
+	es.AddWithExpire("a", 3 * time.Hour)
+	es.Add("B")
+	es.AddWithExpire("b", time.Hour)
+
+	lower := es.Map(strings.ToLower)
+	if got := sorted(lower.GetAll()); !equalStrings(got, []string{"a", "b"}) {
+		t.Fatalf("Map = %v, want [a b]", got)
+	}
+	// the merged elements keep the later expiration time
+	if ttl, _ := lower.TTL("a"); ttl < 2 * time.Hour {
+		t.Fatalf("ttl of a = %v, want the later one", ttl)
+	}
+	if _, ok := lower.TTL("b"); ok {
+		t.Fatal("b got a ttl, want none like B")
+	}
+}
+
+
+func TestGroupBy(t *testing.T) {
+	es := NewSet[string]()
+	es.AddAll("t1:a", "t1:b", "t2:c")
+	es.AddWithExpire("t3:x", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	groups := GroupBy(es, func(elem string) string {
+		return elem[:2]
+	})
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if got := sorted(groups["t1"].GetAll()); !equalStrings(got, []string{"t1:a", "t1:b"}) {
+		t.Fatalf("group t1 = %v", got)
+	}
+	if got := groups["t2"].GetAll(); !equalStrings(got, []string{"t2:c"}) {
+		t.Fatalf("group t2 = %v", got)
+	}
+}
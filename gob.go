@@ -0,0 +1,33 @@
+package eset
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Encodes the unexpired elements with the time they expire at,
+// so that their ttl survives the transfer, e.g. over net/rpc.
+// The elements of an ExpirableSet are interface{},
+// so their concrete types must be registered with gob.Register.
+func(es *Set[T]) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(es.Entries()); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+
+// Replaces the elements of the set with the decoded ones,
+// the options of the set are kept.
+// The elements that expired during the transfer are dropped by the next cleanup.
+func(es *Set[T]) GobDecode(data []byte) error {
+	var entries []Entry[T]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+		return err
+	}
+
+	es.replaceEntries(entries)
+	return nil
+}
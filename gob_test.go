@@ -0,0 +1,55 @@
+package eset
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+)
+
+func TestGob(t *testing.T) {
+	es := NewSet[string]()
+	es.Add("a")
+	es.AddWithExpire("b", time.Hour)
+	es.AddWithExpire("c", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(es); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := NewSet[string]()
+	decoded.Add("d")
+	if err := gob.NewDecoder(&buf).Decode(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := sorted(decoded.GetAll()); !equalStrings(got, []string{"a", "b"}) {
+		t.Fatalf("decoded %v, want [a b]", got)
+	}
+	if ttl, ok := decoded.TTL("b"); !ok || ttl <= 59 * time.Minute {
+		t.Fatalf("ttl of b = %v, %v, want about an hour", ttl, ok)
+	}
+	if _, ok := decoded.TTL("a"); ok {
+		t.Fatal("a got a ttl")
+	}
+}
+
+
+func TestGobExpirableSet(t *testing.T) {
+	es := New()
+	es.Add("a")
+	es.AddWithExpire(1, time.Hour)
+
+	data, err := es.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := New()
+	if err := decoded.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Contains("a") || !decoded.Contains(1) || decoded.Size() != 2 {
+		t.Fatalf("decoded %v, want [a 1]", decoded.GetAll())
+	}
+}
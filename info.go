@@ -0,0 +1,61 @@
+package eset
+
+// MapInfo describes how a set uses its map.
+type MapInfo struct {
+	// the unexpired elements
+	Size int
+	// the expired elements that are not deleted yet
+	Expired int
+	// an estimate of how many elements the map has room for,
+	// maps keep their room after the elements are deleted
+	Capacity int
+	// (Size + Expired) / Capacity, since the expired elements take room as well
+	LoadFactor float64
+}
+
+// Go maps are split into groups of 8 slots,
+// and grow once 7/8 of the slots are used.
+const (
+	mapGroupSize     = 8
+	mapMaxLoadFactor = 7.0 / 8
+)
+
+
+// Returns the number of unexpired elements and the capacity of the set,
+// see MapInfo.
+func(es *Set[T]) Info() (size, capacity int) {
+	info := es.MapInfo()
+	return info.Size, info.Capacity
+}
+
+
+// Returns how the set uses its map.
+// The capacity is estimated from the most elements
+// the map has held, since the runtime doesn't expose it.
+func(es *Set[T]) MapInfo() MapInfo {
+	es.rlock()
+	info := MapInfo{Capacity: mapCapacity(max(es.peak, len(es.elems)))}
+
+	now := es.nowNano()
+	for _, b := range es.elems {
+		if b.isExpired(now) {
+			info.Expired++
+		}
+	}
+	info.Size = len(es.elems) - info.Expired
+	es.runlock()
+
+	info.LoadFactor = float64(info.Size + info.Expired) / float64(info.Capacity)
+	return info
+}
+
+
+// Returns the slots of a map that has held n elements at most.
+func mapCapacity(n int) int {
+	capacity := mapGroupSize
+	for float64(n) > mapMaxLoadFactor * float64(capacity) {
+		capacity <<= 1
+	}
+
+	return capacity
+}
@@ -0,0 +1,40 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCapacity(t *testing.T) {
+	if es := NewSet[int](WithCapacity(100)); es.capacity != 100 {
+		t.Fatalf("capacity = %d, want 100", es.capacity)
+	}
+	if es := NewSetWithCapacity[int](5000); es.capacity != 5000 {
+		t.Fatalf("capacity = %d, want 5000", es.capacity)
+	}
+	if es := FromSlice([]int{1, 2}, 0, WithCapacity(10)); es.capacity != 10 || es.Size() != 2 {
+		t.Fatalf("capacity = %d, want 10", es.capacity)
+	}
+}
+
+
+func TestInfo(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 100; i++ {
+		es.Add(i)
+	}
+	es.AddWithExpire(1000, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if size, capacity := es.Info(); size != 100 || capacity != 128 {
+		t.Fatalf("Info() = %d, %d, want 100, 128", size, capacity)
+	}
+
+	info := es.MapInfo()
+	if info.Size != 100 || info.Expired != 1 || info.Capacity != 128 {
+		t.Fatalf("MapInfo() = %+v", info)
+	}
+	if info.LoadFactor != 101.0 / 128 {
+		t.Fatalf("load factor = %v, want 101/128", info.LoadFactor)
+	}
+}
@@ -0,0 +1,22 @@
+// Package intset provides an expirable set of int64.
+// The elements are kept in a map[int64] directly,
+// so they are not boxed into interface{} like eset.ExpirableSet does,
+// and Add and Contains don't allocate.
+package intset
+
+import "github.com/ichxxx/eset"
+
+type Set = eset.Set[int64]
+
+
+// Returns a set with the options, see eset.Option.
+func New(opts ...eset.Option) *Set {
+	return eset.NewSet[int64](opts...)
+}
+
+
+// Pre-sizes the set to hold capacity elements,
+// see eset.WithCapacity.
+func NewWithCapacity(capacity int, opts ...eset.Option) *Set {
+	return eset.NewSet[int64](append([]eset.Option{eset.WithCapacity(capacity)}, opts...)...)
+}
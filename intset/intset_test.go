@@ -0,0 +1,54 @@
+package intset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ichxxx/eset"
+)
+
+func TestSet(t *testing.T) {
+	es := New()
+	es.Add(1)
+	es.AddWithExpire(2, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if !es.Contains(1) || es.Contains(2) || es.Size() != 1 {
+		t.Fatalf("has %v, want [1]", es.GetAll())
+	}
+
+	sized := NewWithCapacity(100)
+	sized.Add(1)
+	if !sized.Equal(es) {
+		t.Fatalf("has %v, want [1]", sized.GetAll())
+	}
+}
+
+
+func TestContainsAllocs(t *testing.T) {
+	es := New()
+	es.Add(12345678)
+
+	if n := testing.AllocsPerRun(100, func() { es.Contains(12345678) }); n != 0 {
+		t.Fatalf("Contains allocates %v times", n)
+	}
+}
+
+
+func TestOptions(t *testing.T) {
+	es := New(eset.WithMaxSize(2))
+	es.Add(1)
+	es.Add(2)
+	es.Add(3)
+	if es.Size() != 2 || es.Contains(1) {
+		t.Fatalf("has %v, want [2 3]", es.GetAll())
+	}
+
+	sized := NewWithCapacity(100, eset.WithMaxSize(2))
+	sized.Add(1)
+	sized.Add(2)
+	sized.Add(3)
+	if sized.Size() != 2 || sized.Contains(1) {
+		t.Fatalf("has %v, want [2 3]", sized.GetAll())
+	}
+}
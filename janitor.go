@@ -0,0 +1,146 @@
+package eset
+
+import "time"
+
+func(es *Set[T]) startJanitor() {
+	go es.runJanitor(es.janitorInterval)
+}
+
+
+func(es *Set[T]) runJanitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if es.refreshFn != nil {
+				es.refreshAhead()
+			}
+
+			if es.samples > 0 {
+				es.sampleExpired(interval / 4)
+				es.shrink()
+				continue
+			}
+
+			es.cleanExpired()
+		case <-es.stop:
+			return
+		}
+	}
+}
+
+
+// Offers the elements that are about to expire to the refresh func,
+// it's called without holding the lock.
+// Each element is offered once unless it's renewed.
+func(es *Set[T]) refreshAhead() {
+	now := es.now()
+	var due []expireItem[T]
+	es.rlock()
+	es.expires.due(now.Add(es.refreshWindow), func(item expireItem[T]) {
+		if es.elems[item.key] != item.base || es.isExpired(item.base) {
+			return
+		}
+
+		if declined, isDeclined := es.declined[item.key]; !isDeclined || declined != item.base {
+			due = append(due, item)
+		}
+	})
+	es.runlock()
+
+	for _, item := range due {
+		keep, ttl := es.refreshFn(es.elemOf(item.key, item.base))
+		es.lock()
+		// the element may have been changed in the meantime
+		if es.elems[item.key] == item.base {
+			if keep {
+				es.setExpireTime(item.key, item.base, es.now().Add(ttl), ttl)
+			} else {
+				es.declined[item.key] = item.base
+			}
+		}
+		es.unlock()
+	}
+
+	es.lock()
+	for key, b := range es.declined {
+		if es.elems[key] != b {
+			delete(es.declined, key)
+		}
+	}
+	es.unlock()
+}
+
+
+// Checks random samples of the elements and deletes the expired ones,
+// and starts over as long as more than 25% of the samples were expired,
+// until it runs out of the time budget, like Redis does.
+func(es *Set[T]) sampleExpired(budget time.Duration) {
+	start := time.Now()
+	for {
+		es.lock()
+		lockedAt := time.Now()
+		sampled := 0
+		expiredCount := 0
+		// the iteration order of map is random
+		for key, b := range es.elems {
+			if sampled == es.samples {
+				break
+			}
+
+			sampled++
+			if es.isExpired(b) {
+				es.expire(key, b)
+				expiredCount++
+			}
+		}
+		es.countPause(lockedAt)
+		es.unlock()
+
+		if expiredCount * 4 <= sampled || time.Since(start) > budget {
+			return
+		}
+	}
+}
+
+
+// Stops the background goroutines of the set, like the janitor.
+// The set is still usable after it's stopped,
+// and it's safe to call Stop more than once.
+func(es *Set[T]) Stop() {
+	es.stopOnce.Do(func() {
+		if es.stop != nil {
+			close(es.stop)
+		}
+	})
+}
+
+
+// Stops the set like Stop, saves the last auto snapshot if it has one,
+// writes the queued changes of WithWriteBehind to the store,
+// and closes its append-only log if it has one.
+// Returns the first error the log has run into.
+func(es *Set[T]) Close() error {
+	es.Stop()
+	if es.snapshotSink != nil {
+		es.autoSnapshot()
+	}
+
+	es.lock()
+	defer es.unlock()
+
+	if es.writeBehind != nil {
+		es.flushStore(es.writeBehind)
+		es.writeBehind = nil
+	}
+
+	if es.aof == nil {
+		return nil
+	}
+
+	err := es.aof.close()
+	es.aof = nil
+	return err
+}
@@ -0,0 +1,86 @@
+package eset
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Returns the number of elements in the map, expired or not.
+func mapLen[T comparable](es *Set[T]) int {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+	return len(es.elems)
+}
+
+
+func TestJanitor(t *testing.T) {
+	es := NewSet[int](WithJanitor(time.Millisecond))
+	defer es.Stop()
+	es.AddWithExpire(1, time.Millisecond)
+	es.Add(2)
+
+	deadline := time.Now().Add(time.Second)
+	for mapLen(es) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("janitor didn't delete the expired element")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	es.Stop()
+	// stopping again is fine
+	es.Stop()
+}
+
+
+func TestSampledExpiration(t *testing.T) {
+	es := NewSet[int](WithSampledExpiration(time.Millisecond, 20))
+	defer es.Stop()
+	for i := 0; i < 1000; i++ {
+		es.AddWithExpire(i, time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		es.Add(-i - 1)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for mapLen(es) > 50 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d elements left, want the expired ones sampled out", mapLen(es))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if es.Size() != 10 {
+		t.Fatalf("size = %d, want 10", es.Size())
+	}
+}
+
+
+func TestRefreshAhead(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[interface{}]int{}
+	es := NewSet[int](WithRefreshAhead(20 * time.Millisecond, func(elem interface{}) (bool, time.Duration) {
+		mu.Lock()
+		calls[elem]++
+		mu.Unlock()
+		return elem.(int) == 1, time.Hour
+	}))
+	defer es.Stop()
+	es.AddWithExpire(1, 30 * time.Millisecond)
+	es.AddWithExpire(2, 30 * time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	if !es.Contains(1) || es.Contains(2) {
+		t.Fatalf("has %v, want [1] refreshed", es.GetAll())
+	}
+	if ttl, _ := es.TTL(1); ttl < 59 * time.Minute {
+		t.Fatalf("ttl of 1 = %v, want the new ttl", ttl)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls[1] != 1 || calls[2] != 1 {
+		t.Fatalf("calls = %v, want one for each element", calls)
+	}
+}
@@ -0,0 +1,97 @@
+package eset
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// jsonEntry is the encoding of an element in the JSON stream,
+// ExpireAt is the unix nano it expires at, omitted if it has no ttl.
+type jsonEntry[T comparable] struct {
+	Elem     T     `json:"elem"`
+	ExpireAt int64 `json:"expireAt,omitempty"`
+}
+
+
+// Writes the unexpired elements to w as a JSON array of
+// {"elem": ..., "expireAt": ...} objects one at a time,
+// so that encoding a huge set doesn't buffer it in memory.
+// The read lock is held until all of them are written,
+// unless the set is copy-on-write.
+func(es *Set[T]) EncodeTo(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	elems, done := es.view()
+	defer done()
+
+	bw.WriteByte('[')
+	now := es.nowNano()
+	first := true
+	for key, base := range elems {
+		if base.isExpired(now) {
+			continue
+		}
+
+		data, err := json.Marshal(jsonEntry[T]{Elem: es.elemOf(key, base), ExpireAt: base.expireAt})
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+	}
+	bw.WriteByte(']')
+
+	return bw.Flush()
+}
+
+
+// Reads a JSON array written by EncodeTo from r
+// and adds its elements to the set one batch at a time,
+// so that decoding a huge set doesn't buffer it in memory.
+// The elements of an ExpirableSet are decoded into the types
+// encoding/json picks for interface{}, e.g. float64 for numbers,
+// so a set of ints doesn't contain them after a round trip, use a typed set instead,
+// and the arrays and objects, which aren't hashable, fail it with ErrUnhashable.
+// The elements added before an error are kept.
+func(es *Set[T]) DecodeFrom(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	if token, err := dec.Token(); err != nil {
+		return err
+	} else if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("eset: expected a JSON array, got %v", token)
+	}
+
+	batch := make([]Entry[T], 0, loadBatchSize)
+	for dec.More() {
+		var entry jsonEntry[T]
+		if err := dec.Decode(&entry); err != nil {
+			es.addEntries(batch)
+			return err
+		}
+		if !es.hashable(entry.Elem) {
+			es.addEntries(batch)
+			return fmt.Errorf("eset: %w: %T", ErrUnhashable, entry.Elem)
+		}
+
+		batch = append(batch, Entry[T]{Elem: entry.Elem})
+		if entry.ExpireAt != 0 {
+			batch[len(batch)-1].ExpireAt = time.Unix(0, entry.ExpireAt)
+		}
+		if len(batch) == loadBatchSize {
+			es.addEntries(batch)
+			batch = batch[:0]
+		}
+	}
+
+	es.addEntries(batch)
+	_, err := dec.Token()
+	return err
+}
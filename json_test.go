@@ -0,0 +1,81 @@
+package eset
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeTo(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 3000; i++ {
+		es.Add(i)
+	}
+	es.AddWithExpire(-1, time.Hour)
+	es.AddWithExpire(-2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := es.EncodeTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := NewSet[int]()
+	if err := decoded.DecodeFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Size() != 3001 || decoded.Contains(-2) {
+		t.Fatalf("decoded %d elements, want 3001", decoded.Size())
+	}
+	if _, ok := decoded.TTL(-1); !ok {
+		t.Fatal("-1 lost its ttl")
+	}
+	if _, ok := decoded.TTL(0); ok {
+		t.Fatal("0 got a ttl")
+	}
+}
+
+
+func TestDecodeFromErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"not an array", `{"elem": "a"}`, nil},
+		{"bad element", `[{"elem": "a"}, {"elem": 1}]`, []string{"a"}},
+		{"truncated", `[{"elem": "a"}, {"elem": "b"}`, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := NewSet[string]()
+			if err := es.DecodeFrom(strings.NewReader(tt.input)); err == nil {
+				t.Fatal("decoded without an error")
+			}
+			// the elements decoded before the error are kept
+			if got := sorted(es.GetAll()); !equalStrings(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+
+func TestDecodeFromUnhashable(t *testing.T) {
+	es := NewSet[interface{}]()
+	err := es.DecodeFrom(strings.NewReader(`[{"elem": "a"}, {"elem": [1, 2]}, {"elem": "b"}]`))
+	if !errors.Is(err, ErrUnhashable) {
+		t.Fatalf("error = %v, want ErrUnhashable", err)
+	}
+	if es.Size() != 1 || !es.Contains("a") {
+		t.Fatalf("has %v, want [a]", es.GetAll())
+	}
+
+	err = es.DecodeFrom(strings.NewReader(`[{"elem": {"b": 1}}]`))
+	if !errors.Is(err, ErrUnhashable) {
+		t.Fatalf("error = %v, want ErrUnhashable", err)
+	}
+}
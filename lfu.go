@@ -0,0 +1,150 @@
+package eset
+
+import "sync"
+
+// lfu keeps the elements in a list for each frequency,
+// so that finding the least frequently used one is O(1),
+// ties are broken by recency.
+// The frequencies are halved after every
+// lfuDecayFactor accesses per element.
+type lfu[T comparable] struct {
+	mutex    sync.Mutex
+	entries  map[T]*lfuEntry[T]
+	buckets  map[int]*linkedList[T]
+	minFreq  int
+	accesses int
+}
+
+type lfuEntry[T comparable] struct {
+	freq int
+	node *node[T]
+}
+
+const lfuDecayFactor = 10
+
+
+func newLFU[T comparable]() *lfu[T] {
+	return &lfu[T]{
+		entries: make(map[T]*lfuEntry[T]),
+		buckets: make(map[int]*linkedList[T]),
+	}
+}
+
+
+func(l *lfu[T]) add(key T) {
+	l.mutex.Lock()
+	if entry, isExist := l.entries[key]; isExist {
+		l.touch(entry)
+	} else {
+		l.entries[key] = &lfuEntry[T]{freq: 1, node: l.bucket(1).pushFront(key)}
+		l.minFreq = 1
+	}
+	l.mutex.Unlock()
+}
+
+
+func(l *lfu[T]) access(key T) {
+	l.mutex.Lock()
+	if entry, isExist := l.entries[key]; isExist {
+		l.touch(entry)
+	}
+	l.mutex.Unlock()
+}
+
+
+func(l *lfu[T]) remove(key T) {
+	l.mutex.Lock()
+	if entry, isExist := l.entries[key]; isExist {
+		l.unlink(entry)
+		delete(l.entries, key)
+	}
+	l.mutex.Unlock()
+}
+
+
+func(l *lfu[T]) victim() (key T, ok bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(l.entries) == 0 {
+		return key, false
+	}
+
+	if _, isExist := l.buckets[l.minFreq]; !isExist {
+		l.resetMinFreq()
+	}
+
+	return l.buckets[l.minFreq].back().key, true
+}
+
+
+// Moves the entry to the next frequency.
+func(l *lfu[T]) touch(entry *lfuEntry[T]) {
+	l.unlink(entry)
+	if entry.freq == l.minFreq {
+		if _, isExist := l.buckets[entry.freq]; !isExist {
+			l.minFreq++
+		}
+	}
+
+	entry.freq++
+	l.bucket(entry.freq).insertFront(entry.node)
+
+	l.accesses++
+	if l.accesses >= lfuDecayFactor * len(l.entries) {
+		l.decay()
+	}
+}
+
+
+// Removes the entry from the list of its frequency,
+// the list is dropped once it's empty.
+func(l *lfu[T]) unlink(entry *lfuEntry[T]) {
+	bucket := l.buckets[entry.freq]
+	bucket.remove(entry.node)
+	if bucket.len == 0 {
+		delete(l.buckets, entry.freq)
+	}
+}
+
+
+func(l *lfu[T]) bucket(freq int) *linkedList[T] {
+	bucket, isExist := l.buckets[freq]
+	if !isExist {
+		bucket = newLinkedList[T]()
+		l.buckets[freq] = bucket
+	}
+
+	return bucket
+}
+
+
+// Halves all the frequencies.
+func(l *lfu[T]) decay() {
+	l.accesses = 0
+	old := l.buckets
+	l.buckets = make(map[int]*linkedList[T], len(old))
+	for _, entry := range l.entries {
+		entry.freq = (entry.freq + 1) / 2
+	}
+
+	// keep the recency order within the lists
+	for freq := range old {
+		for n := old[freq].back(); n != nil; n = old[freq].back() {
+			old[freq].remove(n)
+			l.bucket(l.entries[n.key].freq).insertFront(n)
+		}
+	}
+
+	l.resetMinFreq()
+}
+
+
+func(l *lfu[T]) resetMinFreq() {
+	l.minFreq = 0
+	for freq := range l.buckets {
+		if l.minFreq == 0 || freq < l.minFreq {
+			l.minFreq = freq
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package eset
+
+// linkedList is a doubly linked list of keys,
+// like container/list but without boxing the keys into interface{}.
+type linkedList[T comparable] struct {
+	// root.next is the front and root.prev is the back
+	root node[T]
+	len  int
+}
+
+type node[T comparable] struct {
+	key        T
+	prev, next *node[T]
+}
+
+
+func newLinkedList[T comparable]() *linkedList[T] {
+	l := &linkedList[T]{}
+	l.root.prev = &l.root
+	l.root.next = &l.root
+	return l
+}
+
+
+func(l *linkedList[T]) pushFront(key T) *node[T] {
+	n := &node[T]{key: key}
+	l.insertFront(n)
+	return n
+}
+
+
+func(l *linkedList[T]) insertFront(n *node[T]) {
+	n.prev = &l.root
+	n.next = l.root.next
+	l.root.next.prev = n
+	l.root.next = n
+	l.len++
+}
+
+
+func(l *linkedList[T]) remove(n *node[T]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev, n.next = nil, nil
+	l.len--
+}
+
+
+func(l *linkedList[T]) moveToFront(n *node[T]) {
+	l.remove(n)
+	l.insertFront(n)
+}
+
+
+// Returns nil if the list is empty.
+func(l *linkedList[T]) back() *node[T] {
+	if l.len == 0 {
+		return nil
+	}
+
+	return l.root.prev
+}
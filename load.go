@@ -0,0 +1,165 @@
+package eset
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how many entries a loader buffers for a shard
+// before adding them under its lock
+const loadBatchSize = 256
+
+
+// Adds the entries received from the source with the workers
+// until the source is closed or the context is done,
+// e.g. to warm up a large set at startup.
+// Each worker buffers the entries by shard,
+// so that the lock of a shard is taken once per batch.
+// Entries whose ExpireAt is zero never expire, even if the set has a default ttl.
+// Returns the error of the context if it's done before the source is closed,
+// the entries buffered by then are still added.
+func(s *ShardedSet[T]) LoadParallel(ctx context.Context, source <-chan Entry[T], workers int) error {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.load(ctx, source)
+		}()
+	}
+
+	wg.Wait()
+	return ctx.Err()
+}
+
+
+func(s *ShardedSet[T]) load(ctx context.Context, source <-chan Entry[T]) {
+	batches := make([][]Entry[T], len(s.shards))
+	defer func() {
+		for i, batch := range batches {
+			if len(batch) > 0 {
+				s.shards[i].addEntries(batch)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-source:
+			if !ok {
+				return
+			}
+
+			i := s.index(entry.Elem)
+			batches[i] = append(batches[i], entry)
+			if len(batches[i]) == loadBatchSize {
+				s.shards[i].addEntries(batches[i])
+				batches[i] = batches[i][:0]
+			}
+		}
+	}
+}
+
+
+// Adds the lines read from r as elements one batch at a time,
+// e.g. to load a blocklist file.
+// The lines are trimmed of surrounding spaces and the empty ones are skipped.
+// All of them expire ttl after the load starts,
+// or never if ttl is 0 and the set has no default ttl,
+// so loading a refreshed list with a ttl longer than the refresh interval
+// drops the lines that are no longer in it.
+// It only works with sets of string or interface{}.
+// The lines added before an error are kept.
+func(es *Set[T]) LoadLines(r io.Reader, ttl time.Duration) error {
+	expireAt, err := es.linesExpireAt(ttl)
+	if err != nil {
+		return err
+	}
+
+	batch := make([]Entry[T], 0, loadBatchSize)
+	err = scanLines(r, func(line string) {
+		batch = append(batch, Entry[T]{Elem: interface{}(line).(T), ExpireAt: expireAt})
+		if len(batch) == loadBatchSize {
+			es.addEntries(batch)
+			batch = batch[:0]
+		}
+	})
+
+	es.addEntries(batch)
+	return err
+}
+
+
+// Same as Set.LoadLines, the lines are buffered by shard
+// so that the lock of a shard is taken once per batch.
+func(s *ShardedSet[T]) LoadLines(r io.Reader, ttl time.Duration) error {
+	expireAt, err := s.shards[0].linesExpireAt(ttl)
+	if err != nil {
+		return err
+	}
+
+	batches := make([][]Entry[T], len(s.shards))
+	err = scanLines(r, func(line string) {
+		elem := interface{}(line).(T)
+		i := s.index(elem)
+		batches[i] = append(batches[i], Entry[T]{Elem: elem, ExpireAt: expireAt})
+		if len(batches[i]) == loadBatchSize {
+			s.shards[i].addEntries(batches[i])
+			batches[i] = batches[i][:0]
+		}
+	})
+
+	for i, batch := range batches {
+		if len(batch) > 0 {
+			s.shards[i].addEntries(batch)
+		}
+	}
+	return err
+}
+
+
+// Returns the time the lines loaded with ttl expire at,
+// zero if they never expire,
+// or an error if the elements of the set can't be strings.
+func(es *Set[T]) linesExpireAt(ttl time.Duration) (time.Time, error) {
+	if _, ok := interface{}("").(T); !ok {
+		var elem T
+		return time.Time{}, fmt.Errorf("eset: can't load lines into a set of %T", elem)
+	}
+
+	if ttl <= 0 {
+		ttl = es.defaultTTL
+	}
+	if ttl > 0 {
+		return es.now().Add(ttl), nil
+	}
+
+	return time.Time{}, nil
+}
+
+
+// Calls fn with each line read from r,
+// trimmed of surrounding spaces, skipping the empty ones.
+func scanLines(r io.Reader, fn func(line string)) error {
+	scanner := bufio.NewScanner(r)
+	// long enough for any domain or address
+	scanner.Buffer(make([]byte, 64 << 10), 1 << 20)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			fn(line)
+		}
+	}
+
+	return scanner.Err()
+}
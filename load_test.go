@@ -0,0 +1,101 @@
+package eset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadParallel(t *testing.T) {
+	s := NewSharded[int]()
+	defer s.Close()
+
+	source := make(chan Entry[int])
+	go func() {
+		for i := 0; i < 10000; i++ {
+			var expireAt time.Time
+			if i % 2 == 0 {
+				expireAt = time.Now().Add(time.Hour)
+			}
+			source <- Entry[int]{Elem: i, ExpireAt: expireAt}
+		}
+		close(source)
+	}()
+
+	if err := s.LoadParallel(context.Background(), source, 4); err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 10000 {
+		t.Fatalf("size = %d, want 10000", s.Size())
+	}
+	if _, ok := s.TTL(2); !ok {
+		t.Fatal("loaded element lost its ttl")
+	}
+	if _, ok := s.TTL(3); ok {
+		t.Fatal("loaded element got a ttl")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.LoadParallel(ctx, make(chan Entry[int]), 2); err != context.Canceled {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+}
+
+
+func TestLoadLines(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&input, "host-%d\r\n\n", i % 7)
+	}
+	input.WriteString("  last  ")
+
+	es := NewSet[string]()
+	if err := es.LoadLines(strings.NewReader(input.String()), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// the lines are trimmed and the blank ones skipped
+	if es.Size() != 8 || !es.Contains("last") {
+		t.Fatalf("loaded %v", es.GetAll())
+	}
+	if ttl, ok := es.TTL("host-0"); !ok || ttl <= 59 * time.Minute {
+		t.Fatalf("ttl of host-0 = %v, %v, want about an hour", ttl, ok)
+	}
+
+	boxed := New()
+	if err := boxed.LoadLines(strings.NewReader("a\nb"), 0); err != nil || boxed.Size() != 2 {
+		t.Fatalf("loaded %v, %v", boxed.GetAll(), err)
+	}
+	if _, ok := boxed.TTL("a"); ok {
+		t.Fatal("a got a ttl")
+	}
+
+	if err := NewSet[int]().LoadLines(strings.NewReader("1"), 0); err == nil {
+		t.Fatal("loaded lines into a set of ints")
+	}
+}
+
+
+func TestShardedLoadLines(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&input, " host-%d\n\n", i)
+	}
+
+	s := NewSharded[string](WithShards(4))
+	if err := s.LoadLines(strings.NewReader(input.String()), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 3000 || !s.Contains("host-0") || !s.Contains("host-2999") {
+		t.Fatalf("loaded %d lines, want 3000", s.Size())
+	}
+	if ttl, ok := s.TTL("host-0"); !ok || ttl <= 59 * time.Minute {
+		t.Fatalf("ttl of host-0 = %v, %v, want about an hour", ttl, ok)
+	}
+
+	if err := NewSharded[int]().LoadLines(strings.NewReader("1"), 0); err == nil {
+		t.Fatal("loaded lines into a sharded set of ints")
+	}
+}
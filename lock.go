@@ -0,0 +1,35 @@
+package eset
+
+// The lock of the set is taken through these,
+// so that an unsafe set can skip it, see NewUnsafe.
+// The write lock is released by unlock.
+
+func(es *Set[T]) lock() {
+	if !es.unsync {
+		es.mutex.Lock()
+	}
+}
+
+
+func(es *Set[T]) rlock() {
+	if !es.unsync {
+		es.mutex.RLock()
+	}
+}
+
+
+func(es *Set[T]) runlock() {
+	if !es.unsync {
+		es.mutex.RUnlock()
+	}
+}
+
+
+func(es *Set[T]) tryLock() bool {
+	return es.unsync || es.mutex.TryLock()
+}
+
+
+func(es *Set[T]) tryRLock() bool {
+	return es.unsync || es.mutex.TryRLock()
+}
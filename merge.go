@@ -0,0 +1,49 @@
+package eset
+
+// TTLMergePolicy decides the expiration time of an element
+// that set operations find in both sets.
+type TTLMergePolicy int
+
+const (
+	// Keeps the expiration time the element has in the receiver,
+	// or in the earlier set for UnionAll and IntersectAll.
+	KeepLeftTTL TTLMergePolicy = iota
+	// Keeps the later expiration time, no ttl is the latest.
+	KeepMaxTTL
+	// Keeps the earlier expiration time, no ttl is the latest.
+	KeepMinTTL
+	// Drops the ttl, the element only expires
+	// when it reaches the max lifetime of the set if it has one.
+	DropTTL
+	// Keeps the expiration time the element has in the argument,
+	// or in the later set for UnionAll and IntersectAll.
+	KeepRightTTL
+)
+
+
+// Returns the base of an element found in both sets,
+// left is the one of the receiver.
+func(es *Set[T]) mergeBase(left, right base) base {
+	return mergeBaseWith(es.ttlMerge, left, right)
+}
+
+
+func mergeBaseWith(policy TTLMergePolicy, left, right base) base {
+	switch policy {
+	case KeepRightTTL:
+		return right
+	case KeepMaxTTL:
+		if left.hasTTL() && (!right.hasTTL() || right.expireAt > left.expireAt) {
+			return right
+		}
+	case KeepMinTTL:
+		if right.hasTTL() && (!left.hasTTL() || right.expireAt < left.expireAt) {
+			return right
+		}
+	case DropTTL:
+		left.expireAt = left.deadline()
+		left.ttl = 0
+	}
+
+	return left
+}
@@ -0,0 +1,63 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLMergePolicy(t *testing.T) {
+	// Returns a set of 1 with the ttl, none if it's 0.
+	set := func(policy TTLMergePolicy, ttl time.Duration) *Set[int] {
+		es := NewSet[int](WithTTLMergePolicy(policy))
+		if ttl > 0 {
+			es.AddWithExpire(1, ttl)
+		} else {
+			es.Add(1)
+		}
+		return es
+	}
+
+	tests := []struct {
+		name   string
+		result func() *Set[int]
+		// the range of the ttl of 1 in the result, 0 for none
+		min, max time.Duration
+	}{
+		{"left", func() *Set[int] { return set(KeepLeftTTL, time.Hour).Union(set(0, 2 * time.Hour)) }, 59 * time.Minute, time.Hour},
+		{"max", func() *Set[int] { return set(KeepMaxTTL, time.Hour).Union(set(0, 2 * time.Hour)) }, 119 * time.Minute, 2 * time.Hour},
+		{"max of no ttl", func() *Set[int] { return set(KeepMaxTTL, time.Hour).Union(set(0, 0)) }, 0, 0},
+		{"min", func() *Set[int] { return set(KeepMinTTL, 0).Intersect(set(0, time.Hour)) }, 59 * time.Minute, time.Hour},
+		{"drop", func() *Set[int] { return set(DropTTL, time.Hour).Intersect(set(0, time.Hour)) }, 0, 0},
+		{"max in place", func() *Set[int] {
+			es := set(KeepMaxTTL, time.Hour)
+			es.UnionWith(set(0, 3 * time.Hour))
+			return es
+		}, 179 * time.Minute, 3 * time.Hour},
+		{"min in place", func() *Set[int] {
+			es := set(KeepMinTTL, 3 * time.Hour)
+			es.IntersectWith(set(0, time.Hour))
+			return es
+		}, 59 * time.Minute, time.Hour},
+		{"max of all", func() *Set[int] {
+			return UnionAll(set(KeepMaxTTL, time.Hour), set(0, 5 * time.Hour), set(0, 2 * time.Hour))
+		}, 299 * time.Minute, 5 * time.Hour},
+		{"min of all", func() *Set[int] {
+			return IntersectAll(set(KeepMinTTL, 5 * time.Hour), set(0, time.Hour), set(0, 2 * time.Hour))
+		}, 59 * time.Minute, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttl, ok := tt.result().TTL(1)
+			if tt.max == 0 {
+				if ok {
+					t.Fatalf("ttl = %v, want none", ttl)
+				}
+				return
+			}
+			if !ok || ttl < tt.min || ttl > tt.max {
+				t.Fatalf("ttl = %v, want between %v and %v", ttl, tt.min, tt.max)
+			}
+		})
+	}
+}
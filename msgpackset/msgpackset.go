@@ -0,0 +1,139 @@
+// Package msgpackset encodes expirable sets with MessagePack,
+// it's a separate package so that eset doesn't depend on a msgpack library.
+// A set is encoded as an array of [elem, expireAt] pairs,
+// expireAt is the unix nano the element expires at, 0 if it has no ttl.
+package msgpackset
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/ichxxx/eset"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Set wraps a set so that it can be a field of a struct
+// encoded with msgpack, e.g. a NATS message.
+type Set[T comparable] struct {
+	*eset.Set[T]
+}
+
+
+// Encodes the unexpired elements of the set.
+func Marshal[T comparable](es *eset.Set[T]) ([]byte, error) {
+	return msgpack.Marshal(Set[T]{es})
+}
+
+
+// Returns a set that has the decoded elements,
+// opts are applied to it.
+// The elements of an ExpirableSet are decoded into
+// the types msgpack picks for interface{}, e.g. int8 for small integers,
+// and the arrays and maps, which aren't hashable, fail it with eset.ErrUnhashable.
+func Unmarshal[T comparable](data []byte, opts ...eset.Option) (*eset.Set[T], error) {
+	entries, err := decodeEntries[T](msgpack.NewDecoder(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	return eset.FromEntries(entries, opts...), nil
+}
+
+
+func(s Set[T]) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if s.Set == nil {
+		return enc.EncodeNil()
+	}
+
+	entries := s.Entries()
+	if err := enc.EncodeArrayLen(len(entries)); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := enc.EncodeArrayLen(2); err != nil {
+			return err
+		}
+		if err := enc.Encode(entry.Elem); err != nil {
+			return err
+		}
+
+		var expireAt int64
+		if !entry.ExpireAt.IsZero() {
+			expireAt = entry.ExpireAt.UnixNano()
+		}
+		if err := enc.EncodeInt(expireAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+
+// Decodes into a new set without options.
+func(s *Set[T]) DecodeMsgpack(dec *msgpack.Decoder) error {
+	entries, err := decodeEntries[T](dec)
+	if err != nil {
+		return err
+	}
+
+	s.Set = eset.FromEntries(entries)
+	return nil
+}
+
+
+// Hides the UnmarshalBinary of the embedded set,
+// which is promoted to Set itself since the embedded field is a pointer,
+// so msgpack would call it instead of DecodeMsgpack for a Set field of a struct.
+// Decodes the layout of eset.Set.MarshalBinary into a new set without options.
+func(s *Set[T]) UnmarshalBinary(data []byte) error {
+	es := eset.NewSet[T]()
+	if err := es.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	s.Set = es
+	return nil
+}
+
+
+func decodeEntries[T comparable](dec *msgpack.Decoder) ([]eset.Entry[T], error) {
+	n, err := dec.DecodeArrayLen()
+	if err != nil || n < 0 {
+		// -1 is nil
+		return nil, err
+	}
+
+	entries := make([]eset.Entry[T], 0, n)
+	for i := 0; i < n; i++ {
+		pairLen, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		if pairLen != 2 {
+			return nil, fmt.Errorf("msgpackset: expected a pair, got %d values", pairLen)
+		}
+
+		var entry eset.Entry[T]
+		if err := dec.Decode(&entry.Elem); err != nil {
+			return nil, err
+		}
+		if !reflect.ValueOf(&entry.Elem).Elem().Comparable() {
+			return nil, fmt.Errorf("msgpackset: %w: %T", eset.ErrUnhashable, entry.Elem)
+		}
+
+		expireAt, err := dec.DecodeInt64()
+		if err != nil {
+			return nil, err
+		}
+		if expireAt != 0 {
+			entry.ExpireAt = time.Unix(0, expireAt)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,82 @@
+package msgpackset
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ichxxx/eset"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestMarshal(t *testing.T) {
+	es := eset.NewSet[string]()
+	es.Add("a")
+	es.AddWithExpire("b", time.Hour)
+	es.AddWithExpire("c", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	data, err := Marshal(es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Unmarshal[string](data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := decoded.GetAll()
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("decoded %v, want [a b]", got)
+	}
+	if _, ok := decoded.TTL("b"); !ok {
+		t.Fatal("b lost its ttl")
+	}
+	if _, ok := decoded.TTL("a"); ok {
+		t.Fatal("a got a ttl")
+	}
+}
+
+
+func TestField(t *testing.T) {
+	type message struct {
+		Name string
+		Tags Set[string]
+	}
+
+	tags := eset.NewSet[string]()
+	tags.Add("go")
+	data, err := msgpack.Marshal(message{Name: "n", Tags: Set[string]{tags}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded message
+	if err := msgpack.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Name != "n" || decoded.Tags.Set == nil || !decoded.Tags.Contains("go") {
+		t.Fatalf("decoded %+v", decoded)
+	}
+}
+
+
+func TestUnhashable(t *testing.T) {
+	data, err := msgpack.Marshal([]interface{}{
+		[]interface{}{"a", 0},
+		[]interface{}{[]int{1, 2}, 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Unmarshal[interface{}](data); !errors.Is(err, eset.ErrUnhashable) {
+		t.Fatalf("error = %v, want eset.ErrUnhashable", err)
+	}
+	var s Set[interface{}]
+	if err := msgpack.Unmarshal(data, &s); !errors.Is(err, eset.ErrUnhashable) {
+		t.Fatalf("error = %v, want eset.ErrUnhashable", err)
+	}
+}
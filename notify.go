@@ -0,0 +1,105 @@
+package eset
+
+// EvictReason tells why an element left the set.
+type EvictReason int
+
+const (
+	// The ttl of the element lapsed.
+	Expired EvictReason = iota
+	// The element was evicted because the set was full.
+	Evicted
+	// The element was removed by Remove.
+	Removed
+	// The element was replaced by another one with the same key,
+	// e.g. by Add or Update.
+	Replaced
+)
+
+type eviction[T comparable] struct {
+	elem   T
+	reason EvictReason
+}
+
+
+func(r EvictReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Evicted:
+		return "evicted"
+	case Removed:
+		return "removed"
+	case Replaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+
+// Records an element that left the set,
+// if anyone watches it for the reason.
+// It must be called while holding the write lock.
+func(es *Set[T]) pend(elem T, reason EvictReason) {
+	if es.stats != nil {
+		es.stats.left(reason)
+	}
+
+	if es.onEvict != nil || reason == Expired && (es.onExpire != nil || es.expiredCh != nil) {
+		es.pending = append(es.pending, eviction[T]{elem: elem, reason: reason})
+	}
+}
+
+
+// Releases the write lock,
+// and then notifies the watchers of the elements
+// that left the set while it was held.
+// The changes are published first if the set is copy-on-write,
+// and written to the append-only log if it has one.
+// The store is swept once released if the set is cleared, see sweepStore.
+func(es *Set[T]) unlock() {
+	if es.copyOnWrite && es.stale {
+		es.publish()
+	}
+
+	if es.aof != nil {
+		es.flushAOF()
+	}
+
+	pending := es.pending
+	es.pending = nil
+	sweep := es.storeCleared
+	es.storeCleared = false
+	if !es.unsync {
+		es.mutex.Unlock()
+	}
+
+	if sweep {
+		es.sweepStore()
+	}
+
+	for _, e := range pending {
+		if e.reason == Expired {
+			es.notifyExpired(e.elem)
+		}
+
+		if es.onEvict != nil {
+			es.onEvict(e.elem, e.reason)
+		}
+	}
+}
+
+
+func(es *Set[T]) notifyExpired(elem T) {
+	if es.onExpire != nil {
+		es.onExpire(elem)
+	}
+
+	if es.expiredCh != nil {
+		select {
+		case es.expiredCh <- elem:
+		default:
+			es.expiredDropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOnExpire(t *testing.T) {
+	var expired []interface{}
+	var es *Set[int]
+	es = NewSet[int](WithOnExpire(func(elem interface{}) {
+		expired = append(expired, elem)
+		// the callback can use the set
+		es.Add(99)
+	}))
+	es.AddWithExpire(1, time.Millisecond)
+	es.AddWithExpire(2, time.Millisecond)
+	es.Add(3)
+	time.Sleep(5 * time.Millisecond)
+
+	es.GetAll()
+	if len(expired) != 2 || !es.Contains(99) {
+		t.Fatalf("expired %v, want 1 and 2", expired)
+	}
+
+	es.Remove(3)
+	if len(expired) != 2 {
+		t.Fatal("removed element is reported as expired")
+	}
+}
+
+
+func TestExpiredChan(t *testing.T) {
+	es := NewSet[int](WithExpiredChan(1))
+	es.AddWithExpire(1, time.Millisecond)
+	es.AddWithExpire(2, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	es.Size()
+
+	select {
+	case elem := <-es.Expired():
+		if elem != 1 && elem != 2 {
+			t.Fatalf("received %d", elem)
+		}
+	default:
+		t.Fatal("nothing received")
+	}
+	if es.ExpiredDropped() != 1 {
+		t.Fatalf("dropped %d, want 1", es.ExpiredDropped())
+	}
+}
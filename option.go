@@ -0,0 +1,459 @@
+package eset
+
+import (
+	"crypto/cipher"
+	"io"
+	"time"
+)
+
+// Option configures a set when it is created.
+type Option func(*config)
+
+type config struct {
+	keyFunc         func(elem interface{}) interface{}
+	janitorInterval time.Duration
+	wheelTick       time.Duration
+	wheelSlots      int
+	wheelLevels     int
+	onExpire        func(elem interface{})
+	expiredBuffer   int
+	sliding         bool
+	defaultTTL      time.Duration
+	maxLifetime     time.Duration
+	samples         int
+	quarantine      bool
+	refreshWindow   time.Duration
+	refreshFn       func(elem interface{}) (keep bool, newTTL time.Duration)
+	maxSize         int
+	policy          EvictionPolicy
+	weigher         func(elem interface{}) int64
+	maxWeight       int64
+	tinyLFU         bool
+	onEvict         func(elem interface{}, reason EvictReason)
+	shrinkRatio     float64
+	capacity        int
+	shards          int
+	readMostly      bool
+	copyOnWrite     bool
+	clockPrecision  time.Duration
+	expireOnRead    bool
+	ttlMerge        TTLMergePolicy
+	snapshotSink     SnapshotSink
+	snapshotInterval time.Duration
+	compress         func(w io.Writer) io.WriteCloser
+	decompress       func(r io.Reader) (io.Reader, error)
+	// the cipher of WithSnapshotKey
+	aead             cipher.AEAD
+	// the Store[T] of WithStore
+	backend          interface{}
+	writeBehindSize     int
+	writeBehindInterval time.Duration
+	changeLog           bool
+	maxRemoved          int
+	countStats          bool
+	// the set doesn't lock, only set by NewUnsafe
+	unsync          bool
+}
+
+
+// Returns the configuration for the sets derived from a set,
+// like the results of set operations,
+// which don't inherit the callbacks, the capacity, the auto snapshot, the store,
+// the change log or the stats.
+func(c config) inherited() config {
+	c.onExpire = nil
+	c.onEvict = nil
+	c.refreshFn = nil
+	c.capacity = 0
+	c.snapshotSink = nil
+	c.backend = nil
+	c.changeLog = false
+	c.countStats = false
+	return c
+}
+
+
+// Keys the elements by a user-supplied hash instead of the elements themselves,
+// so that values which are not comparable,
+// like structs containing slices or maps, can be stored in the set.
+// The original elements are still returned by GetAll and ForEach,
+// and elements with the same hash are treated as the same element.
+// It only works with sets of interface{}, such as ExpirableSet.
+func WithHasher(hasher func(elem interface{}) uint64) Option {
+	return func(c *config) {
+		c.keyFunc = func(elem interface{}) interface{} {
+			return hasher(elem)
+		}
+	}
+}
+
+
+// Deduplicates and looks up the elements by the key fn derives from them,
+// e.g. store whole User structs but keyed by User.ID.
+// fn is also applied to the arguments of Contains, Remove
+// and the other lookups, so if it returns keys unchanged,
+// they accept either the full element or the key:
+//
+//	eset.WithKeyFunc(func(elem interface{}) interface{} {
+//		if user, ok := elem.(User); ok {
+//			return user.ID
+//		}
+//		return elem
+//	})
+//
+// The returned keys must be comparable.
+// It only works with sets of interface{}, such as ExpirableSet.
+func WithKeyFunc(fn func(elem interface{}) interface{}) Option {
+	return func(c *config) {
+		c.keyFunc = fn
+	}
+}
+
+
+// Starts a janitor goroutine that deletes
+// the expired elements every interval,
+// otherwise they are only deleted when Size, GetAll or ForEach runs.
+// Call Stop or Close to stop the janitor when the set is no longer used.
+// Sets returned by set operations don't have a janitor.
+func WithJanitor(interval time.Duration) Option {
+	return func(c *config) {
+		c.janitorInterval = interval
+	}
+}
+
+
+// Indexes the elements with ttl in a hierarchical timing wheel
+// instead of a min-heap, which keeps the cost of expiration O(1) amortized
+// for sets with lots of short-lived elements.
+// The wheel has levels levels of slots slots each,
+// and the slots of the lowest level are tick long,
+// so it covers tick * slots^levels ahead,
+// elements expiring later than that are carried over at each round.
+// Elements are expired at the end of the tick they fall in.
+// It's mostly useful with WithJanitor.
+func WithTimingWheel(tick time.Duration, slots, levels int) Option {
+	return func(c *config) {
+		if tick <= 0 || slots <= 0 || levels <= 0 {
+			panic("eset: tick, slots and levels of timing wheel must be positive")
+		}
+
+		c.wheelTick = tick
+		c.wheelSlots = slots
+		c.wheelLevels = levels
+	}
+}
+
+
+// Calls fn with each element that expires,
+// whether it's found by the janitor or by a method like GetAll.
+// fn is called without holding the lock of the set,
+// after the element has been deleted from it.
+func WithOnExpire(fn func(elem interface{})) Option {
+	return func(c *config) {
+		c.onExpire = fn
+	}
+}
+
+
+// Sends the expired elements to the channel returned by Expired,
+// which is buffered with size.
+// If the channel is full, the elements are dropped
+// instead of blocking the set, see ExpiredDropped.
+func WithExpiredChan(size int) Option {
+	return func(c *config) {
+		c.expiredBuffer = size
+	}
+}
+
+
+// Renews the ttl of an element each time Contains finds it,
+// so that only the elements that are not accessed for their ttl expire.
+// Use Peek to check an element without renewing it.
+func WithSlidingExpiration() Option {
+	return func(c *config) {
+		c.sliding = true
+	}
+}
+
+
+// Expires the elements added by Add
+// after they are not accessed for the timeout,
+// it's the same as WithDefaultTTL in sliding expiration mode.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.defaultTTL = timeout
+		c.sliding = true
+	}
+}
+
+
+// Expires every element after the lifetime since it's added,
+// no matter whether it has ttl or is renewed.
+// Together with WithIdleTimeout,
+// elements expire after being idle or after the lifetime,
+// whichever comes first.
+func WithMaxLifetime(lifetime time.Duration) Option {
+	return func(c *config) {
+		c.maxLifetime = lifetime
+	}
+}
+
+
+// Makes Add give the elements the ttl,
+// instead of adding them without expiration.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.defaultTTL = ttl
+	}
+}
+
+
+// Starts a janitor that deletes the expired elements every interval
+// by checking random samples of them, like Redis does.
+// If more than 25% of the samples were expired, it samples again,
+// so the cost of each run is bounded even for huge sets.
+func WithSampledExpiration(interval time.Duration, samples int) Option {
+	return func(c *config) {
+		c.janitorInterval = interval
+		c.samples = samples
+	}
+}
+
+
+// Keeps the expired elements in a quarantine after deleting them,
+// until they are collected by DrainExpired.
+// The quarantine isn't bounded, so it must be drained regularly.
+func WithExpiredQuarantine() Option {
+	return func(c *config) {
+		c.quarantine = true
+	}
+}
+
+
+// Calls fn with each element that expires within the window,
+// so that it can be renewed before it expires:
+// if fn returns keep, the element gets the new ttl,
+// otherwise it's left to expire and won't be offered again.
+// fn is called by the janitor without holding the lock of the set,
+// if there's no janitor, one is started with half the window as interval.
+func WithRefreshAhead(window time.Duration, fn func(elem interface{}) (keep bool, newTTL time.Duration)) Option {
+	return func(c *config) {
+		c.refreshWindow = window
+		c.refreshFn = fn
+	}
+}
+
+
+// Limits the number of elements in the set,
+// when it's full, adding an element evicts another one
+// chosen by the eviction policy, which is LRU by default.
+// Elements that have expired but not been deleted yet count towards the size.
+func WithMaxSize(size int) Option {
+	return func(c *config) {
+		c.maxSize = size
+	}
+}
+
+
+// Sets the eviction policy of a set with a max size.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(c *config) {
+		c.policy = policy
+	}
+}
+
+
+// Weighs each element, e.g. by its size in bytes,
+// so that WithMaxWeight limits the total weight of the set.
+func WithWeigher(weigher func(elem interface{}) int64) Option {
+	return func(c *config) {
+		c.weigher = weigher
+	}
+}
+
+
+// Limits the total weight of the elements in the set,
+// elements are evicted by the eviction policy
+// until the weight is within the limit.
+// Every element weighs 1 unless the set has a weigher.
+func WithMaxWeight(weight int64) Option {
+	return func(c *config) {
+		c.maxWeight = weight
+	}
+}
+
+
+// Puts a TinyLFU admission filter in front of the eviction policy:
+// when the set is full, a new element is only added
+// if it has been seen more often than the element it would evict,
+// otherwise it's dropped, so Add may not add it.
+// Both Add and Contains count as seeing an element.
+func WithTinyLFU() Option {
+	return func(c *config) {
+		c.tinyLFU = true
+	}
+}
+
+
+// Calls fn with each element that leaves the set
+// and the reason it leaves, whether it expires, is evicted,
+// removed or replaced by another element with the same key.
+// Like WithOnExpire, fn is called without holding the lock of the set.
+// Clear doesn't call it.
+func WithOnEvict(fn func(elem interface{}, reason EvictReason)) Option {
+	return func(c *config) {
+		c.onEvict = fn
+	}
+}
+
+
+// Limits the memory the elements take,
+// elements are evicted by the eviction policy
+// once their estimated size exceeds the budget.
+// The estimate is rough: it's the size of each element,
+// following its strings, slices, maps and pointers,
+// plus a fixed overhead per element.
+// It replaces the weigher of the set.
+func WithMaxBytes(bytes int64) Option {
+	return func(c *config) {
+		c.weigher = estimateSize
+		c.maxWeight = bytes
+	}
+}
+
+
+// Sets the fraction of the most elements a set has held
+// below which its map is reallocated to release memory,
+// since Go maps never shrink.
+// It's 0.25 by default and 0 disables it.
+// The map is reallocated incrementally during cleanups,
+// and never if it has held less than 1024 elements.
+func WithShrinkRatio(ratio float64) Option {
+	return func(c *config) {
+		c.shrinkRatio = ratio
+	}
+}
+
+
+// Pre-sizes the set to hold n elements without growing.
+func WithCapacity(n int) Option {
+	return func(c *config) {
+		c.capacity = n
+	}
+}
+
+
+// Sets the number of shards of a set created by NewSharded,
+// it's ignored by the other constructors.
+// It panics if n is not positive.
+func WithShards(n int) Option {
+	return func(c *config) {
+		if n <= 0 {
+			panic("eset: the number of shards must be positive")
+		}
+
+		c.shards = n
+	}
+}
+
+
+// Mirrors the elements into a sync.Map,
+// so that Contains, Peek and the ttl getters don't take the lock,
+// which suits sets that are read much more often than written.
+// Writes get slower as they update both.
+// Contains still takes the lock in sliding expiration mode
+// or if the set has a max size or max weight,
+// since the accesses must be recorded.
+func WithReadMostly() Option {
+	return func(c *config) {
+		c.readMostly = true
+	}
+}
+
+
+// Makes the set copy-on-write: every write publishes
+// a new copy of the map, and the readers like Contains, GetAll,
+// ForEach and the set operations read the latest copy without the lock,
+// so they never wait for the writers nor for each other.
+// Each write copies the whole set, so it only suits sets
+// that are rarely written, like allowlists loaded at startup.
+// Expired elements are not deleted by the readers.
+func WithCopyOnWrite() Option {
+	return func(c *config) {
+		c.copyOnWrite = true
+	}
+}
+
+
+// Caches the current time and updates it every precision,
+// so that checking the expiration of many elements,
+// e.g. by GetAll or ForEach, doesn't read the clock for each of them.
+// Elements may outlive their ttl by up to precision.
+// The clock is stopped by Stop, and then the set reads the time again.
+func WithCoarseClock(precision time.Duration) Option {
+	return func(c *config) {
+		c.clockPrecision = precision
+	}
+}
+
+
+// Makes Contains delete the expired element it comes across
+// right away, taking the write lock for it,
+// so that sets that are mostly read clean themselves
+// instead of keeping the expired elements until the next cleanup.
+func WithExpireOnRead() Option {
+	return func(c *config) {
+		c.expireOnRead = true
+	}
+}
+
+
+// Sets how Union, Intersect and their variants decide the expiration time
+// of an element that is in both sets, it's KeepLeftTTL by default.
+// The policy of the receiver is used, or of the first set for UnionAll and IntersectAll.
+func WithTTLMergePolicy(policy TTLMergePolicy) Option {
+	return func(c *config) {
+		c.ttlMerge = policy
+	}
+}
+
+
+// Saves the set to a snapshot file at path every interval
+// in the background, and once more when it's closed,
+// see SaveFile. The set is restored from the file when it's created.
+// The previous snapshot is kept at path.prev,
+// which the set is restored from if the latest one can't be read.
+// It's ignored by sharded sets, whose shards can't share a file.
+// See WithSnapshotSink to save them somewhere else than the local disk.
+func WithAutoSnapshot(path string, interval time.Duration) Option {
+	return func(c *config) {
+		c.snapshotSink = &fileSink{path: path}
+		c.snapshotInterval = interval
+	}
+}
+
+
+// Compresses the snapshot files and the append-only log of the set,
+// e.g. with gzip:
+//
+//	eset.WithCompression(
+//		func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+//		func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+//	)
+//
+// The log is compressed in segments, one each time the lock is released,
+// so decompress must read concatenated streams, like gzip and zstd do.
+// Each segment has the overhead of a stream header,
+// so the log only shrinks if the writes come in batches, e.g. by AddAll.
+// If the reader it returns is an io.Closer, it's closed after reading.
+func WithCompression(compress func(w io.Writer) io.WriteCloser, decompress func(r io.Reader) (io.Reader, error)) Option {
+	return func(c *config) {
+		c.compress = compress
+		c.decompress = decompress
+	}
+}
+
+
+func withoutLock(c *config) {
+	c.unsync = true
+}
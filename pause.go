@@ -0,0 +1,71 @@
+package eset
+
+import "time"
+
+// Returns the current time of the set,
+// which stands still while the expiration is paused.
+func(es *Set[T]) now() time.Time {
+	return time.Unix(0, es.nowNano())
+}
+
+
+// Returns the current time of the set in unix nano,
+// it's the cached time if the set has a coarse clock.
+func(es *Set[T]) nowNano() int64 {
+	if pausedAt := es.pausedAt.Load(); pausedAt != 0 {
+		return pausedAt
+	}
+
+	if now := es.clock.Load(); now != 0 {
+		return now
+	}
+
+	return time.Now().UnixNano()
+}
+
+
+func(es *Set[T]) isExpired(b base) bool {
+	return b.isExpired(es.nowNano())
+}
+
+
+// Stops the elements from expiring,
+// e.g. during a deploy or a bulk migration.
+// Calling it again while paused does nothing.
+func(es *Set[T]) PauseExpiration() {
+	es.lock()
+	es.pausedAt.CompareAndSwap(0, time.Now().UnixNano())
+	es.unlock()
+}
+
+
+// Lets the elements expire again after PauseExpiration,
+// their expiration time is put off by how long it was paused,
+// so they keep the ttl they had when it was paused.
+func(es *Set[T]) ResumeExpiration() {
+	es.lock()
+	defer es.unlock()
+
+	pausedAt := es.pausedAt.Load()
+	if pausedAt == 0 {
+		return
+	}
+
+	paused := time.Since(time.Unix(0, pausedAt))
+	for key, b := range es.elems {
+		if !b.hasTTL() {
+			continue
+		}
+
+		newBase := b
+		newBase.expireAt += int64(paused)
+		if b.deadline() != 0 {
+			newBase.ext = &extra{deadline: b.deadline() + int64(paused), elem: b.ext.elem}
+		}
+		es.elems[key] = newBase
+		es.changed(key, newBase, false)
+	}
+
+	es.pausedAt.Store(0)
+	es.rebuildExpires()
+}
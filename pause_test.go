@@ -0,0 +1,26 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauseExpiration(t *testing.T) {
+	es := NewSet[int]()
+	es.AddWithExpire(1, 20 * time.Millisecond)
+
+	es.PauseExpiration()
+	time.Sleep(30 * time.Millisecond)
+	if !es.Contains(1) || es.Size() != 1 {
+		t.Fatal("element expired while paused")
+	}
+
+	es.ResumeExpiration()
+	if ttl, ok := es.TTL(1); !ok || ttl < 10 * time.Millisecond {
+		t.Fatalf("ttl = %v after resume, want the time it had left", ttl)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if es.Contains(1) {
+		t.Fatal("element didn't expire after resume")
+	}
+}
@@ -0,0 +1,89 @@
+// Package promcollector exports the metrics of expirable sets to Prometheus,
+// it's a separate package so that eset doesn't depend on the Prometheus client.
+// The counters come from eset.Stats, so the sets should be created with eset.WithStats.
+package promcollector
+
+import (
+	"github.com/ichxxx/eset"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Source is a set the collector reads,
+// which both *eset.Set[T] and *eset.ShardedSet[T] are.
+type Source interface {
+	Len() int
+	MaxSize() int
+	Stats() eset.Stats
+}
+
+// Collector is a prometheus.Collector of a set,
+// its metrics have a set label with the name of the set,
+// so that the collectors of several sets can be registered together.
+type Collector struct {
+	set Source
+
+	size         *prometheus.Desc
+	capacity     *prometheus.Desc
+	hitRatio     *prometheus.Desc
+	hits         *prometheus.Desc
+	misses       *prometheus.Desc
+	adds         *prometheus.Desc
+	removes      *prometheus.Desc
+	expirations  *prometheus.Desc
+	evictions    *prometheus.Desc
+	cleanupPause *prometheus.Desc
+}
+
+
+// Returns a collector of the set, whose set label is name.
+func New(set Source, name string) *Collector {
+	labels := prometheus.Labels{"set": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("eset", "", metric), help, nil, labels)
+	}
+
+	return &Collector{
+		set:          set,
+		size:         desc("size", "The elements in the set, including the expired ones not deleted yet."),
+		capacity:     desc("capacity", "The max size of the set, 0 if it's unbounded."),
+		hitRatio:     desc("hit_ratio", "The ratio of the lookups that found the element."),
+		hits:         desc("hits_total", "The lookups that found the element."),
+		misses:       desc("misses_total", "The lookups that didn't find the element."),
+		adds:         desc("adds_total", "The elements added."),
+		removes:      desc("removes_total", "The elements removed."),
+		expirations:  desc("expirations_total", "The elements deleted once they expired."),
+		evictions:    desc("evictions_total", "The elements evicted because the set was full."),
+		cleanupPause: desc("cleanup_pause_seconds", "The time the lock was held to delete the expired elements."),
+	}
+}
+
+
+func(c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.capacity
+	ch <- c.hitRatio
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.adds
+	ch <- c.removes
+	ch <- c.expirations
+	ch <- c.evictions
+	ch <- c.cleanupPause
+}
+
+
+func(c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.set.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(c.set.Len()))
+	ch <- prometheus.MustNewConstMetric(c.capacity, prometheus.GaugeValue, float64(c.set.MaxSize()))
+	ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, stats.HitRatio())
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.adds, prometheus.CounterValue, float64(stats.Adds))
+	ch <- prometheus.MustNewConstMetric(c.removes, prometheus.CounterValue, float64(stats.Removes))
+	ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(stats.Expirations))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	// a summary without quantiles, the mean pause is its sum over its count
+	ch <- prometheus.MustNewConstSummary(c.cleanupPause, stats.Cleanups, stats.CleanupPause.Seconds(), nil)
+}
@@ -0,0 +1,52 @@
+package promcollector
+
+import (
+	"testing"
+
+	"github.com/ichxxx/eset"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollector(t *testing.T) {
+	es := eset.NewSet[string](eset.WithStats(), eset.WithMaxSize(10))
+	es.Add("a")
+	es.Contains("a")
+	es.Contains("b")
+	sharded := eset.NewSharded[string](eset.WithStats())
+	sharded.Add("a")
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(New(es, "users"), New(sharded, "sessions"))
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name := family.GetName() + "/" + metric.GetLabel()[0].GetValue()
+			if metric.GetCounter() != nil {
+				got[name] = metric.GetCounter().GetValue()
+			} else {
+				got[name] = metric.GetGauge().GetValue()
+			}
+		}
+	}
+
+	want := map[string]float64{
+		"eset_size/users":          1,
+		"eset_capacity/users":      10,
+		"eset_hits_total/users":    1,
+		"eset_misses_total/users":  1,
+		"eset_hit_ratio/users":     0.5,
+		"eset_adds_total/users":    1,
+		"eset_adds_total/sessions": 1,
+		"eset_size/sessions":       1,
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Fatalf("%s = %v, want %v", name, got[name], value)
+		}
+	}
+}
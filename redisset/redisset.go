@@ -0,0 +1,239 @@
+// Package redisset provides a set with the API of eset.Set[string]
+// on top of Redis, so that the instances of a service can share it.
+// The elements are the members of a sorted set,
+// scored by the unix milli they expire at, +inf if they have no ttl.
+// The expired members are ignored by the reads,
+// and deleted by DeleteExpired.
+//
+// Redis can fail, unlike a map, so the methods
+// that can't return an error report it by Err.
+package redisset
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Sets the score of a member that exists and hasn't expired.
+// KEYS[1] is the key, ARGV is the member, now and the new score.
+var expireScript = redis.NewScript(`
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not score or (score ~= 'inf' and tonumber(score) <= tonumber(ARGV[2])) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+type Set struct {
+	client redis.Cmdable
+	key    string
+	// the timeout of each command, 0 for no timeout
+	timeout time.Duration
+	err     atomic.Pointer[error]
+}
+
+
+// Returns a set stored under the key,
+// each command times out after timeout unless it's 0.
+func New(client redis.Cmdable, key string, timeout time.Duration) *Set {
+	return &Set{client: client, key: key, timeout: timeout}
+}
+
+
+// Add an element to the set normally.
+// If the element is existed,
+// its expiration time will be cleared if it has.
+func(s *Set) Add(elem string) {
+	s.AddAll(elem)
+}
+
+
+// Add an element to the set with an expiration time.
+func(s *Set) AddWithExpire(elem string, expireTime time.Duration) {
+	s.AddAllWithExpire(expireTime, elem)
+}
+
+
+// Add the elements to the set with one command.
+func(s *Set) AddAll(elems ...string) {
+	s.add(math.Inf(1), elems)
+}
+
+
+// Add the elements to the set with an expiration time with one command.
+func(s *Set) AddAllWithExpire(expireTime time.Duration, elems ...string) {
+	s.add(score(time.Now().Add(expireTime)), elems)
+}
+
+
+func(s *Set) add(score float64, elems []string) {
+	if len(elems) == 0 {
+		return
+	}
+
+	members := make([]redis.Z, len(elems))
+	for i, elem := range elems {
+		members[i] = redis.Z{Score: score, Member: elem}
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	s.failed(s.client.ZAdd(ctx, s.key, members...).Err())
+}
+
+
+// Returns true if the element is in the set.
+func(s *Set) Contains(elem string) bool {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	expireAt, err := s.client.ZScore(ctx, s.key, elem).Result()
+	if err != redis.Nil {
+		s.failed(err)
+	}
+
+	return err == nil && expireAt > score(time.Now())
+}
+
+
+// Remove an element in the set.
+// Returns false if the element doesn't exist.
+func(s *Set) Remove(elem string) bool {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	removed, err := s.client.ZRem(ctx, s.key, elem).Result()
+	s.failed(err)
+	return removed > 0
+}
+
+
+// Set the expiration time of an existed element,
+// whether it has one or not.
+// Returns false if the element doesn't exist.
+func(s *Set) Expire(elem string, expireTime time.Duration) bool {
+	return s.setScore(elem, score(time.Now().Add(expireTime)))
+}
+
+
+// Remove the expiration time of an existed element.
+// Returns false if the element doesn't exist.
+func(s *Set) Persist(elem string) bool {
+	return s.setScore(elem, math.Inf(1))
+}
+
+
+func(s *Set) setScore(elem string, newScore float64) bool {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	now := strconv.FormatFloat(score(time.Now()), 'f', -1, 64)
+	ok, err := expireScript.Run(ctx, s.client, []string{s.key}, elem, now, newScore).Int()
+	s.failed(err)
+	return ok == 1
+}
+
+
+// Returns the remaining time to live of the element.
+// ok is false if the element doesn't exist or doesn't have ttl.
+func(s *Set) TTL(elem string) (ttl time.Duration, ok bool) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	expireAt, err := s.client.ZScore(ctx, s.key, elem).Result()
+	if err != redis.Nil {
+		s.failed(err)
+	}
+	if err != nil || math.IsInf(expireAt, 1) {
+		return 0, false
+	}
+
+	ttl = time.Until(time.UnixMilli(int64(expireAt)))
+	return ttl, ttl > 0
+}
+
+
+// Returns the number of unexpired elements.
+func(s *Set) Size() int {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	size, err := s.client.ZCount(ctx, s.key, unexpiredMin(), "+inf").Result()
+	s.failed(err)
+	return int(size)
+}
+
+
+// Returns all unexpired elements.
+func(s *Set) GetAll() []string {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	elems, err := s.client.ZRangeByScore(ctx, s.key, &redis.ZRangeBy{Min: unexpiredMin(), Max: "+inf"}).Result()
+	s.failed(err)
+	return elems
+}
+
+
+// Remove all elements in the set.
+func(s *Set) Clear() {
+	ctx, cancel := s.context()
+	defer cancel()
+	s.failed(s.client.Del(ctx, s.key).Err())
+}
+
+
+// Deletes the expired elements from Redis,
+// e.g. from a cron job of one of the instances.
+// Returns how many were deleted.
+func(s *Set) DeleteExpired() (int64, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	now := strconv.FormatFloat(score(time.Now()), 'f', -1, 64)
+	return s.client.ZRemRangeByScore(ctx, s.key, "-inf", now).Result()
+}
+
+
+// Returns the last error of Redis, nil if there is none.
+func(s *Set) Err() error {
+	if err := s.err.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
+
+
+func(s *Set) failed(err error) {
+	if err != nil {
+		s.err.Store(&err)
+	}
+}
+
+
+func(s *Set) context() (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(context.Background(), s.timeout)
+	}
+
+	return context.Background(), func() {}
+}
+
+
+// Returns the score of the time, which is its unix milli.
+func score(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+
+// Returns the exclusive min score of the unexpired elements.
+func unexpiredMin() string {
+	return "(" + strconv.FormatFloat(score(time.Now()), 'f', -1, 64)
+}
@@ -0,0 +1,96 @@
+package redisset
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newSet(t *testing.T) (*Set, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client, "set", time.Second), server
+}
+
+
+func TestSet(t *testing.T) {
+	s, _ := newSet(t)
+	s.Add("a")
+	s.AddWithExpire("b", time.Hour)
+	s.AddWithExpire("c", time.Millisecond)
+	s.AddAll("d", "e")
+	time.Sleep(5 * time.Millisecond)
+
+	if !s.Contains("a") || !s.Contains("b") || s.Contains("c") {
+		t.Fatalf("has %v", s.GetAll())
+	}
+	got := s.GetAll()
+	sort.Strings(got)
+	if s.Size() != 4 || len(got) != 4 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("has %v, size %d, want [a b d e]", got, s.Size())
+	}
+
+	if !s.Remove("e") || s.Remove("e") {
+		t.Fatal("Remove doesn't report whether the element existed")
+	}
+	if n, err := s.DeleteExpired(); err != nil || n != 1 {
+		t.Fatalf("DeleteExpired() = %d, %v, want 1", n, err)
+	}
+
+	s.Clear()
+	if s.Size() != 0 || s.Err() != nil {
+		t.Fatalf("size = %d after Clear, error %v", s.Size(), s.Err())
+	}
+}
+
+
+func TestTTL(t *testing.T) {
+	s, _ := newSet(t)
+	s.Add("a")
+	s.AddWithExpire("b", time.Hour)
+
+	if _, ok := s.TTL("a"); ok {
+		t.Fatal("a has a ttl")
+	}
+	if ttl, ok := s.TTL("b"); !ok || ttl <= 59 * time.Minute {
+		t.Fatalf("TTL(b) = %v, %v, want about an hour", ttl, ok)
+	}
+
+	// adding again clears the ttl
+	s.Add("b")
+	if _, ok := s.TTL("b"); ok {
+		t.Fatal("b still has a ttl after Add")
+	}
+
+	if !s.Expire("a", time.Minute) || s.Expire("missing", time.Minute) {
+		t.Fatal("Expire doesn't report whether the element exists")
+	}
+	if ttl, ok := s.TTL("a"); !ok || ttl > time.Minute {
+		t.Fatalf("TTL(a) = %v, %v, want a minute", ttl, ok)
+	}
+	if !s.Persist("a") {
+		t.Fatal("Persist failed")
+	}
+	if _, ok := s.TTL("a"); ok {
+		t.Fatal("a has a ttl after Persist")
+	}
+}
+
+
+func TestErr(t *testing.T) {
+	s, server := newSet(t)
+	s.Add("a")
+	server.Close()
+
+	if s.Contains("a") {
+		t.Fatal("contains an element of a closed server")
+	}
+	if s.Err() == nil {
+		t.Fatal("the error of Redis isn't reported")
+	}
+}
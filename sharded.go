@@ -0,0 +1,390 @@
+package eset
+
+import (
+	"hash/maphash"
+	"time"
+)
+
+const defaultShards = 16
+
+// ShardedSet spreads its elements over several sets by their hash,
+// each with its own lock, so that goroutines working on
+// different elements rarely wait for each other.
+// Max size, max weight and capacity are split evenly among the shards,
+// so the elements are evicted by shard rather than by the whole set.
+// Each shard has its own janitor if the set has one.
+type ShardedSet[T comparable] struct {
+	shards []*Set[T]
+	seed   maphash.Seed
+}
+
+
+// Returns a sharded set whose elements are of type T,
+// it has 16 shards unless set by WithShards.
+func NewSharded[T comparable](opts ...Option) *ShardedSet[T] {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	n := c.shards
+	if n == 0 {
+		n = defaultShards
+	}
+
+	opts = append(opts[:len(opts):len(opts)], func(c *config) {
+		c.maxSize = divCeil(c.maxSize, n)
+		c.maxWeight = (c.maxWeight + int64(n) - 1) / int64(n)
+		c.capacity = divCeil(c.capacity, n)
+		// all the shards send to one channel
+		c.expiredBuffer = 0
+		c.snapshotSink = nil
+	})
+
+	s := &ShardedSet[T]{
+		shards: make([]*Set[T], n),
+		seed:   maphash.MakeSeed(),
+	}
+
+	var expiredCh chan T
+	if c.expiredBuffer > 0 {
+		expiredCh = make(chan T, c.expiredBuffer)
+	}
+
+	for i := range s.shards {
+		es := newSet[T](0, opts)
+		es.expiredCh = expiredCh
+		if es.store != nil {
+			es.owns = func(elem T) bool {
+				return s.index(elem) == i
+			}
+		}
+		es.start()
+		s.shards[i] = es
+	}
+
+	return s
+}
+
+
+func divCeil(x, n int) int {
+	return (x + n - 1) / n
+}
+
+
+// Returns the shard that the element belongs to.
+func(s *ShardedSet[T]) shard(elem T) *Set[T] {
+	return s.shards[s.index(elem)]
+}
+
+
+func(s *ShardedSet[T]) index(elem T) int {
+	var hash uint64
+	if keyFunc := s.shards[0].keyFunc; keyFunc != nil {
+		hash = maphash.Comparable(s.seed, keyFunc(elem))
+	} else {
+		hash = maphash.Comparable(s.seed, elem)
+	}
+
+	return int(hash % uint64(len(s.shards)))
+}
+
+
+func(s *ShardedSet[T]) Add(elem T) {
+	s.shard(elem).Add(elem)
+}
+
+
+func(s *ShardedSet[T]) AddWithExpire(elem T, expireTime time.Duration) {
+	s.shard(elem).AddWithExpire(elem, expireTime)
+}
+
+
+func(s *ShardedSet[T]) AddWithExpireAt(elem T, expireAt time.Time) {
+	s.shard(elem).AddWithExpireAt(elem, expireAt)
+}
+
+
+// Add the elements to the set,
+// holding the lock of each shard once.
+func(s *ShardedSet[T]) AddAll(elems ...T) {
+	for i, group := range s.group(elems) {
+		if len(group) > 0 {
+			s.shards[i].AddAll(group...)
+		}
+	}
+}
+
+
+// Add the elements to the set with an expiration time,
+// holding the lock of each shard once.
+func(s *ShardedSet[T]) AddAllWithExpire(expireTime time.Duration, elems ...T) {
+	for i, group := range s.group(elems) {
+		if len(group) > 0 {
+			s.shards[i].AddAllWithExpire(expireTime, group...)
+		}
+	}
+}
+
+
+// Remove the elements in the set,
+// holding the lock of each shard once.
+// Returns how many of them existed.
+func(s *ShardedSet[T]) RemoveAll(elems ...T) int {
+	removed := 0
+	for i, group := range s.group(elems) {
+		if len(group) > 0 {
+			removed += s.shards[i].RemoveAll(group...)
+		}
+	}
+
+	return removed
+}
+
+
+// Groups the elements by their shards.
+func(s *ShardedSet[T]) group(elems []T) [][]T {
+	groups := make([][]T, len(s.shards))
+	for _, elem := range elems {
+		i := s.index(elem)
+		groups[i] = append(groups[i], elem)
+	}
+
+	return groups
+}
+
+
+func(s *ShardedSet[T]) Expire(elem T, expireTime time.Duration) bool {
+	return s.shard(elem).Expire(elem, expireTime)
+}
+
+
+func(s *ShardedSet[T]) ExpireAt(elem T, expireAt time.Time) bool {
+	return s.shard(elem).ExpireAt(elem, expireAt)
+}
+
+
+func(s *ShardedSet[T]) Touch(elem T) bool {
+	return s.shard(elem).Touch(elem)
+}
+
+
+func(s *ShardedSet[T]) Persist(elem T) bool {
+	return s.shard(elem).Persist(elem)
+}
+
+
+func(s *ShardedSet[T]) Remove(elem T) bool {
+	return s.shard(elem).Remove(elem)
+}
+
+
+func(s *ShardedSet[T]) Contains(elem T) bool {
+	return s.shard(elem).Contains(elem)
+}
+
+
+func(s *ShardedSet[T]) Peek(elem T) bool {
+	return s.shard(elem).Peek(elem)
+}
+
+
+func(s *ShardedSet[T]) TTL(elem T) (ttl time.Duration, ok bool) {
+	return s.shard(elem).TTL(elem)
+}
+
+
+func(s *ShardedSet[T]) GetElemTTL(elem T) (ttl float64, err error) {
+	return s.shard(elem).GetElemTTL(elem)
+}
+
+
+func(s *ShardedSet[T]) GetExpireAt(elem T) (expireAt time.Time, ok bool) {
+	return s.shard(elem).GetExpireAt(elem)
+}
+
+
+// Returns the elements of all the shards,
+// the shards are visited one by one,
+// so it's not a snapshot of the whole set at one moment.
+func(s *ShardedSet[T]) GetAll() []T {
+	return s.AppendTo(nil)
+}
+
+
+// Appends the elements of all the shards to dst
+// and returns the extended slice, see GetAll.
+func(s *ShardedSet[T]) AppendTo(dst []T) []T {
+	for _, es := range s.shards {
+		dst = es.AppendTo(dst)
+	}
+
+	return dst
+}
+
+
+// Do something for each elements in the set,
+// the lock of a shard is held while its elements are visited.
+func(s *ShardedSet[T]) ForEach(handler func(T)) {
+	for _, es := range s.shards {
+		es.ForEach(handler)
+	}
+}
+
+
+func(s *ShardedSet[T]) Size() int {
+	size := 0
+	for _, es := range s.shards {
+		size += es.Size()
+	}
+
+	return size
+}
+
+
+// Returns the sum of the max sizes of the shards, see Set.MaxSize.
+func(s *ShardedSet[T]) MaxSize() int {
+	return s.shards[0].MaxSize() * len(s.shards)
+}
+
+
+// Returns the number of elements without taking the locks,
+// see Set.Len.
+func(s *ShardedSet[T]) Len() int {
+	n := 0
+	for _, es := range s.shards {
+		n += es.Len()
+	}
+
+	return n
+}
+
+
+// Returns an estimate of the bytes the set takes,
+// see Set.ApproxBytes.
+func(s *ShardedSet[T]) ApproxBytes() int64 {
+	var bytes int64
+	for _, es := range s.shards {
+		bytes += es.ApproxBytes()
+	}
+
+	return bytes
+}
+
+
+// Returns the sums of the counters of the shards, see Set.Stats.
+func(s *ShardedSet[T]) Stats() Stats {
+	var stats Stats
+	for _, es := range s.shards {
+		shard := es.Stats()
+		stats.Adds += shard.Adds
+		stats.Removes += shard.Removes
+		stats.Hits += shard.Hits
+		stats.Misses += shard.Misses
+		stats.Expirations += shard.Expirations
+		stats.Evictions += shard.Evictions
+		stats.Cleanups += shard.Cleanups
+		stats.CleanupPause += shard.CleanupPause
+	}
+
+	return stats
+}
+
+
+func(s *ShardedSet[T]) Weight() int64 {
+	var weight int64
+	for _, es := range s.shards {
+		weight += es.Weight()
+	}
+
+	return weight
+}
+
+
+// Returns the element that expires first among all the shards.
+func(s *ShardedSet[T]) NextExpiration() (elem T, expireAt time.Time, ok bool) {
+	for _, es := range s.shards {
+		if e, at, isExist := es.NextExpiration(); isExist && (!ok || at.Before(expireAt)) {
+			elem, expireAt, ok = e, at, true
+		}
+	}
+
+	return elem, expireAt, ok
+}
+
+
+// Returns the channel that receives the expired elements of all the shards,
+// see Set.Expired.
+func(s *ShardedSet[T]) Expired() <-chan T {
+	return s.shards[0].Expired()
+}
+
+
+func(s *ShardedSet[T]) DrainExpired() []Entry[T] {
+	var quarantined []Entry[T]
+	for _, es := range s.shards {
+		quarantined = append(quarantined, es.DrainExpired()...)
+	}
+
+	return quarantined
+}
+
+
+func(s *ShardedSet[T]) ExpiredDropped() uint64 {
+	var dropped uint64
+	for _, es := range s.shards {
+		dropped += es.ExpiredDropped()
+	}
+
+	return dropped
+}
+
+
+func(s *ShardedSet[T]) PauseExpiration() {
+	for _, es := range s.shards {
+		es.PauseExpiration()
+	}
+}
+
+
+func(s *ShardedSet[T]) ResumeExpiration() {
+	for _, es := range s.shards {
+		es.ResumeExpiration()
+	}
+}
+
+
+func(s *ShardedSet[T]) ClearEvictedElems() {
+	for _, es := range s.shards {
+		es.ClearEvictedElems()
+	}
+}
+
+
+func(s *ShardedSet[T]) Clear() {
+	for _, es := range s.shards {
+		es.Clear()
+	}
+}
+
+
+func(s *ShardedSet[T]) Stop() {
+	for _, es := range s.shards {
+		es.Stop()
+	}
+}
+
+
+// Closes every shard, see Set.Close,
+// e.g. to write the queued changes of WithWriteBehind to the store.
+// Returns the first error of the shards.
+func(s *ShardedSet[T]) Close() error {
+	var err error
+	for _, es := range s.shards {
+		if closeErr := es.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,114 @@
+package eset
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSharded(t *testing.T) {
+	s := NewSharded[int](WithShards(4), WithMaxSize(100))
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				s.Add(g * 50 + i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// the max size is split between the shards
+	if s.Size() > 100 || s.Size() < 90 {
+		t.Fatalf("size = %d, want about 100", s.Size())
+	}
+
+	s.Clear()
+	s.AddWithExpire(1, time.Millisecond)
+	s.AddWithExpire(2, time.Hour)
+	s.Add(3)
+	if elem, _, ok := s.NextExpiration(); !ok || elem != 1 {
+		t.Fatalf("next expiration = %d, want 1", elem)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if s.Contains(1) || !s.Contains(2) || !s.Contains(3) {
+		t.Fatalf("has %v, want [2 3]", s.GetAll())
+	}
+	if ttl, ok := s.TTL(2); !ok || ttl < 59 * time.Minute {
+		t.Fatalf("ttl of 2 = %v", ttl)
+	}
+	if !s.Remove(3) || s.Remove(3) || s.Size() != 1 {
+		t.Fatalf("has %v after removing 3, want [2]", s.GetAll())
+	}
+}
+
+
+func TestShardedExpiredChan(t *testing.T) {
+	s := NewSharded[int](WithShards(4), WithExpiredChan(10))
+	defer s.Close()
+	s.AddWithExpire(1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	s.Size()
+
+	select {
+	case elem := <-s.Expired():
+		if elem != 1 {
+			t.Fatalf("received %d, want 1", elem)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nothing received")
+	}
+}
+
+
+func TestShardedKeyFunc(t *testing.T) {
+	s := NewSharded[interface{}](WithKeyFunc(func(elem interface{}) interface{} {
+		if u, ok := elem.(user); ok {
+			return u.ID
+		}
+		return elem
+	}))
+	defer s.Close()
+	s.Add(user{1, "alice"})
+	s.Add(user{1, "bob"})
+
+	if !s.Contains(1) || !s.Contains(user{ID: 1}) || s.Size() != 1 {
+		t.Fatalf("has %v, want bob", s.GetAll())
+	}
+}
+
+
+func TestShardedAddAll(t *testing.T) {
+	s := NewSharded[int]()
+	defer s.Close()
+	s.AddAll(1, 2, 3, 4)
+	s.AddAllWithExpire(time.Hour, 5, 6)
+
+	if n := s.RemoveAll(1, 2, 7); n != 2 {
+		t.Fatalf("RemoveAll removed %d, want 2", n)
+	}
+	if got := sortedInts(s.GetAll()); !equalInts(got, []int{3, 4, 5, 6}) {
+		t.Fatalf("has %v, want [3 4 5 6]", got)
+	}
+	if _, ok := s.TTL(6); !ok {
+		t.Fatal("AddAllWithExpire didn't give the ttl")
+	}
+}
+
+
+func TestShardedLen(t *testing.T) {
+	s := NewSharded[int](WithShards(4))
+	defer s.Close()
+	for i := 0; i < 100; i++ {
+		s.Add(i)
+	}
+	s.Remove(0)
+
+	if s.Len() != 99 {
+		t.Fatalf("len = %d, want 99", s.Len())
+	}
+}
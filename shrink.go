@@ -0,0 +1,63 @@
+package eset
+
+// Go maps never give back their buckets,
+// so a map that held many elements is reallocated
+// once most of them are gone.
+const (
+	defaultShrinkRatio = 0.25
+	// smaller maps are not worth reallocating
+	shrinkMinPeak = 1024
+)
+
+
+func(es *Set[T]) isSparse() bool {
+	return es.shrinkRatio > 0 && es.peak >= shrinkMinPeak &&
+		float64(len(es.elems)) < es.shrinkRatio * float64(es.peak)
+}
+
+
+// Reallocates the map if it's sparse.
+// The elements are copied in batches and the lock is released between them,
+// the keys changed in the meantime are recorded in dirty
+// and copied again at the end.
+// It gives up if the map is replaced in the meantime.
+func(es *Set[T]) shrink() {
+	es.lock()
+	if es.dirty != nil || !es.isSparse() {
+		es.unlock()
+		return
+	}
+
+	old, gen := es.elems, es.gen
+	newElems := make(map[T]base, len(old))
+	es.dirty = make(map[T]struct{})
+	n := 0
+	// it's fine to modify a map while ranging over it,
+	// the elements added may be missed but they are dirty
+	for key, b := range old {
+		newElems[key] = b
+		if n++; n % expireBatchSize == 0 {
+			es.unlock()
+			es.lock()
+			if es.gen != gen {
+				es.unlock()
+				return
+			}
+		}
+	}
+
+	for key := range es.dirty {
+		if b, isExist := old[key]; isExist {
+			newElems[key] = b
+		} else {
+			delete(newElems, key)
+		}
+	}
+
+	es.elems = newElems
+	es.peak = len(newElems)
+	es.dirty = nil
+	es.gen++
+	es.unlock()
+}
+
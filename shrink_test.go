@@ -0,0 +1,62 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShrink(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 5000; i++ {
+		es.AddWithExpire(i, time.Millisecond)
+	}
+	for i := 5000; i < 5100; i++ {
+		es.Add(i)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if es.Size() != 100 {
+		t.Fatalf("size = %d, want 100", es.Size())
+	}
+	if es.peak != 100 {
+		t.Fatalf("peak = %d, want the map reallocated for 100 elements", es.peak)
+	}
+
+	disabled := NewSet[int](WithShrinkRatio(0))
+	for i := 0; i < 5000; i++ {
+		disabled.Add(i)
+		disabled.Remove(i)
+	}
+	disabled.Size()
+	if disabled.peak == 0 {
+		t.Fatal("map reallocated with a ratio of 0")
+	}
+}
+
+
+func TestShrinkConcurrentWrites(t *testing.T) {
+	es := NewSet[int]()
+	for i := 0; i < 5000; i++ {
+		es.Add(i)
+	}
+	for i := 0; i < 4900; i++ {
+		es.Remove(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 10000; i < 12000; i++ {
+			es.Add(i)
+			es.Remove(i - 1)
+		}
+		close(done)
+	}()
+	es.shrink()
+	<-done
+	es.shrink()
+
+	// 4900 to 4999 and the last one added
+	if es.Size() != 101 || !es.Contains(11999) || !es.Contains(4900) {
+		t.Fatalf("size = %d, want 101", es.Size())
+	}
+}
@@ -0,0 +1,67 @@
+package eset
+
+// Returns the Jaccard index of the two sets,
+// which is the size of their intersection divided by the size of their union,
+// without building either of them.
+// It's 0 if both sets are empty.
+func(es *Set[T]) Jaccard(other *Set[T]) float64 {
+	size, otherSize, common := es.overlap(other)
+	if size + otherSize == 0 {
+		return 0
+	}
+
+	return float64(common) / float64(size + otherSize - common)
+}
+
+
+// Returns the overlap coefficient of the two sets,
+// which is the size of their intersection divided by the size of the smaller one.
+// It's 0 if either set is empty.
+func(es *Set[T]) Overlap(other *Set[T]) float64 {
+	size, otherSize, common := es.overlap(other)
+	if size == 0 || otherSize == 0 {
+		return 0
+	}
+
+	return float64(common) / float64(min(size, otherSize))
+}
+
+
+// Returns the number of unexpired elements of both sets
+// and how many of them are common.
+// Only the smaller set is looked up in the other one.
+func(es *Set[T]) overlap(other *Set[T]) (size, otherSize, common int) {
+	elems, otherElems, done := viewBoth(es, other)
+	defer done()
+
+	size, otherSize = es.countUnexpired(elems), other.countUnexpired(otherElems)
+	smallEs, smallElems, largeEs, largeElems := es, elems, other, otherElems
+	if len(elems) > len(otherElems) {
+		smallEs, smallElems, largeEs, largeElems = other, otherElems, es, elems
+	}
+
+	now, largeNow := smallEs.nowNano(), largeEs.nowNano()
+	for elem, base := range smallElems {
+		if base.isExpired(now) {
+			continue
+		}
+		if largeBase, isExist := largeElems[elem]; isExist && !largeBase.isExpired(largeNow) {
+			common++
+		}
+	}
+
+	return size, otherSize, common
+}
+
+
+func(es *Set[T]) countUnexpired(elems map[T]base) int {
+	now := es.nowNano()
+	count := 0
+	for _, base := range elems {
+		if !base.isExpired(now) {
+			count++
+		}
+	}
+
+	return count
+}
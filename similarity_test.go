@@ -0,0 +1,25 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimilarity(t *testing.T) {
+	one := setOf(1, 2, 3, 4)
+	other := setOf(3, 4, 5)
+	other.AddWithExpire(1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if j := one.Jaccard(other); j != 2.0 / 5 {
+		t.Fatalf("Jaccard = %v, want 2/5", j)
+	}
+	if o := other.Overlap(one); o != 2.0 / 3 {
+		t.Fatalf("Overlap = %v, want 2/3", o)
+	}
+
+	var empty Set[int]
+	if empty.Jaccard(&empty) != 0 || empty.Overlap(one) != 0 {
+		t.Fatal("similarity of an empty set isn't 0")
+	}
+}
@@ -0,0 +1,76 @@
+package eset
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// SnapshotSink stores the auto snapshots of a set,
+// e.g. in object storage instead of the local disk, see WithSnapshotSink.
+// A snapshot is the content of a snapshot file written by SaveFile.
+type SnapshotSink interface {
+	// Writes a new snapshot by calling write with a writer of it,
+	// e.g. an upload streamed by an io.Pipe.
+	// The latest snapshot must be kept if write returns an error,
+	// which Save returns.
+	Save(write func(w io.Writer) error) error
+	// Returns a reader of the latest snapshot,
+	// or an error wrapping os.ErrNotExist if there is none yet.
+	Open() (io.ReadCloser, error)
+}
+
+
+// Saves the set to the sink every interval in the background,
+// and once more when it's closed, like WithAutoSnapshot does to a file.
+// The set is restored from the latest snapshot in the sink when it's created.
+// It's ignored by sharded sets, whose shards can't share a sink.
+func WithSnapshotSink(sink SnapshotSink, interval time.Duration) Option {
+	return func(c *config) {
+		c.snapshotSink = sink
+		c.snapshotInterval = interval
+	}
+}
+
+
+// Returns the unexpired entries in the latest snapshot of the sink.
+func(es *Set[T]) readSink() ([]Entry[T], error) {
+	r, err := es.snapshotSink.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.decodeSnapshot(data)
+}
+
+
+// fileSink is the sink of WithAutoSnapshot,
+// which keeps the previous snapshot at path.prev.
+type fileSink struct {
+	path string
+}
+
+
+func(s *fileSink) Save(write func(w io.Writer) error) error {
+	// linked to a temporary name and renamed over path.prev,
+	// so that there is always a previous snapshot to recover from.
+	// The link fails if there is no snapshot yet.
+	tmp := s.path + ".prev.tmp"
+	os.Remove(tmp)
+	if os.Link(s.path, tmp) == nil {
+		os.Rename(tmp, s.path + ".prev")
+	}
+
+	return writeFile(s.path, write)
+}
+
+
+func(s *fileSink) Open() (io.ReadCloser, error) {
+	return os.Open(s.path)
+}
@@ -0,0 +1,95 @@
+package eset
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memorySink keeps the last snapshot in memory.
+type memorySink struct {
+	mutex sync.Mutex
+	data  []byte
+}
+
+
+func(s *memorySink) Save(write func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.data = buf.Bytes()
+	s.mutex.Unlock()
+	return nil
+}
+
+
+func(s *memorySink) Open() (io.ReadCloser, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.data == nil {
+		return nil, os.ErrNotExist
+	}
+
+	return io.NopCloser(bytes.NewReader(s.data)), nil
+}
+
+
+// failingSink can't be opened.
+type failingSink struct {
+	memorySink
+}
+
+
+func(s *failingSink) Open() (io.ReadCloser, error) {
+	return nil, errors.New("unavailable")
+}
+
+
+func TestSnapshotSink(t *testing.T) {
+	sink := &memorySink{}
+	es := NewSet[string](WithSnapshotSink(sink, time.Hour))
+	if err := es.SnapshotErr(); err != nil {
+		t.Fatalf("error = %v on an empty sink", err)
+	}
+	es.AddWithExpire("a", time.Hour)
+	es.Add("b")
+	if err := es.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewSet[string](WithSnapshotSink(sink, 5 * time.Millisecond))
+	if got := sorted(restored.GetAll()); !equalStrings(got, []string{"a", "b"}) {
+		t.Fatalf("restored %v, want [a b]", got)
+	}
+	if _, ok := restored.TTL("a"); !ok {
+		t.Fatal("a lost its ttl")
+	}
+
+	restored.Add("c")
+	deadline := time.Now().Add(time.Second)
+	for {
+		latest := NewSet[string](WithSnapshotSink(sink, time.Hour))
+		latest.Stop()
+		if latest.Size() == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no snapshot is saved to the sink in the background")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	restored.Close()
+
+	failed := NewSet[string](WithSnapshotSink(&failingSink{}, time.Hour))
+	defer failed.Stop()
+	if failed.SnapshotErr() == nil {
+		t.Fatal("the error of the sink isn't reported")
+	}
+}
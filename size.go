@@ -0,0 +1,97 @@
+package eset
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// the bytes a map entry and its expiration record take
+// besides the element itself, roughly
+const entryOverhead = 48
+
+// how deep pointers and containers are followed
+const maxSizeDepth = 8
+
+
+// Returns a rough estimate of the bytes an element takes in a set.
+// Strings, slices, maps and pointers are followed,
+// but shared memory is counted each time it's reached.
+func estimateSize(elem interface{}) int64 {
+	return entryOverhead + sizeOf(reflect.ValueOf(elem), 0)
+}
+
+
+func sizeOf(v reflect.Value, depth int) int64 {
+	if !v.IsValid() {
+		return 0
+	}
+
+	size := int64(v.Type().Size())
+	if depth >= maxSizeDepth {
+		return size
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		size += int64(v.Len())
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			size += sizeOf(v.Index(i), depth+1)
+		}
+	case reflect.Array:
+		size = 0
+		for i := 0; i < v.Len(); i++ {
+			size += sizeOf(v.Index(i), depth+1)
+		}
+	case reflect.Struct:
+		size = 0
+		for i := 0; i < v.NumField(); i++ {
+			size += sizeOf(v.Field(i), depth+1)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			size += sizeOf(iter.Key(), depth+1) + sizeOf(iter.Value(), depth+1)
+		}
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			size += sizeOf(v.Elem(), depth+1)
+		}
+	}
+
+	return size
+}
+
+
+// Returns an estimate of the bytes the set takes:
+// the slots of its map, what the elements point to,
+// like the bytes of strings, and the expiration index.
+// The bookkeeping of the eviction policy is not counted.
+// It walks all the elements, so it's not meant for hot paths.
+func(es *Set[T]) ApproxBytes() int64 {
+	es.rlock()
+	defer es.runlock()
+
+	var key T
+	keySize := int64(unsafe.Sizeof(key))
+	// a slot holds the key and the base, plus a control byte
+	slotSize := keySize + int64(unsafe.Sizeof(base{})) + 1
+	bytes := int64(mapCapacity(max(es.peak, len(es.elems)))) * slotSize
+
+	for key, b := range es.elems {
+		bytes += sizeOf(reflect.ValueOf(&key).Elem(), 0) - keySize
+		if b.ext != nil {
+			bytes += sizeOf(reflect.ValueOf(b.ext).Elem(), 0)
+		}
+	}
+
+	if es.expires != nil {
+		bytes += int64(es.expires.len()) * int64(unsafe.Sizeof(expireItem[T]{}))
+	}
+
+	if es.weights != nil {
+		bytes += int64(mapCapacity(len(es.weights))) * (keySize + 8 + 1)
+	}
+
+	return bytes
+}
\ No newline at end of file
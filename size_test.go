@@ -0,0 +1,31 @@
+package eset
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestApproxBytes(t *testing.T) {
+	es := NewSet[string]()
+	for i := 0; i < 1000; i++ {
+		es.Add(fmt.Sprintf("%032d", i))
+	}
+	// the strings, their headers and the bases at least
+	if n := es.ApproxBytes(); n < 1000 * (32 + 16 + 24) || n > 200000 {
+		t.Fatalf("ApproxBytes() = %d", n)
+	}
+
+	boxed := New()
+	for i := 0; i < 1000; i++ {
+		boxed.AddWithExpire(fmt.Sprintf("%032d", i), time.Hour)
+	}
+	if boxed.ApproxBytes() <= es.ApproxBytes() {
+		t.Fatal("boxed elements aren't counted")
+	}
+
+	var empty Set[int]
+	if n := empty.ApproxBytes(); n < 0 {
+		t.Fatalf("ApproxBytes() of the zero value = %d", n)
+	}
+}
@@ -0,0 +1,66 @@
+package eset
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Returns the unexpired elements as the JSON array written by EncodeTo,
+// so that a set, e.g. of strings, can be written to a text or jsonb column,
+// see Scan for reading it back.
+func(es *Set[T]) Value() (driver.Value, error) {
+	var buf bytes.Buffer
+	if err := es.EncodeTo(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.String(), nil
+}
+
+
+// Replaces the elements of the set with the ones in a column
+// written by Value, the options of the set are kept.
+// The elements that have expired since they were written are dropped,
+// and a NULL column empties the set.
+// Like DecodeFrom, the elements of an ExpirableSet are decoded
+// into the types encoding/json picks, e.g. float64 for numbers,
+// and the arrays and objects fail it with ErrUnhashable,
+// the set is left as it is then.
+func(es *Set[T]) Scan(src interface{}) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		es.replaceEntries(nil)
+		return nil
+	case string:
+		data = []byte(src)
+	case []byte:
+		data = src
+	default:
+		return fmt.Errorf("eset: can't scan %T into a set", src)
+	}
+
+	var decoded []jsonEntry[T]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	now := es.nowNano()
+	entries := make([]Entry[T], 0, len(decoded))
+	for _, entry := range decoded {
+		if !es.hashable(entry.Elem) {
+			return fmt.Errorf("eset: %w: %T", ErrUnhashable, entry.Elem)
+		}
+		if entry.ExpireAt == 0 {
+			entries = append(entries, Entry[T]{Elem: entry.Elem})
+		} else if entry.ExpireAt > now {
+			entries = append(entries, Entry[T]{Elem: entry.Elem, ExpireAt: time.Unix(0, entry.ExpireAt)})
+		}
+	}
+
+	es.replaceEntries(entries)
+	return nil
+}
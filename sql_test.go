@@ -0,0 +1,86 @@
+package eset
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	_ driver.Valuer = (*Set[string])(nil)
+	_ sql.Scanner   = (*Set[string])(nil)
+)
+
+
+func TestValueAndScan(t *testing.T) {
+	es := NewSet[string]()
+	es.Add("a")
+	es.AddWithExpire("b", time.Hour)
+	es.AddWithExpire("c", time.Millisecond)
+	value, err := es.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// c expires after it's written
+	time.Sleep(5 * time.Millisecond)
+	var scanned Set[string]
+	if err := scanned.Scan([]byte(value.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if got := sorted(scanned.GetAll()); !equalStrings(got, []string{"a", "b"}) {
+		t.Fatalf("scanned %v, want [a b]", got)
+	}
+	if ttl, ok := scanned.TTL("b"); !ok || ttl <= 59 * time.Minute {
+		t.Fatalf("ttl of b = %v, %v, want about an hour", ttl, ok)
+	}
+
+	if err := scanned.Scan(nil); err != nil || scanned.Size() != 0 {
+		t.Fatalf("scanning NULL left %v, %v", scanned.GetAll(), err)
+	}
+	if err := scanned.Scan(value); err != nil || scanned.Size() != 2 {
+		t.Fatalf("scanning a string gave %v, %v", scanned.GetAll(), err)
+	}
+	if err := scanned.Scan(3); err == nil {
+		t.Fatal("scanned an int")
+	}
+
+	if value, _ := NewSet[string]().Value(); value != "[]" {
+		t.Fatalf("value of an empty set = %v, want []", value)
+	}
+}
+
+
+func TestScanUnhashable(t *testing.T) {
+	es := NewSet[interface{}]()
+	es.Add("a")
+	if err := es.Scan(`[{"elem": "b"}, {"elem": [1, 2]}]`); !errors.Is(err, ErrUnhashable) {
+		t.Fatalf("error = %v, want ErrUnhashable", err)
+	}
+	if es.Size() != 1 || !es.Contains("a") {
+		t.Fatalf("has %v, want [a]", es.GetAll())
+	}
+}
+
+
+func TestScanPaused(t *testing.T) {
+	es := NewSet[string]()
+	es.AddWithExpire("a", 20 * time.Millisecond)
+	value, err := es.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a isn't expired by the clock of the scanning set
+	var scanned Set[string]
+	scanned.PauseExpiration()
+	time.Sleep(30 * time.Millisecond)
+	if err := scanned.Scan(value); err != nil {
+		t.Fatal(err)
+	}
+	if !scanned.Contains("a") {
+		t.Fatalf("scanned %v, want [a]", scanned.GetAll())
+	}
+}
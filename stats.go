@@ -0,0 +1,125 @@
+package eset
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of a set created with WithStats,
+// counted since the set is created.
+type Stats struct {
+	// the elements added, including the ones renewed by adding them again
+	// and the ones restored from snapshots, logs and stores
+	Adds uint64
+	// the elements removed by Remove and its variants, like Pop and RetainAll
+	Removes uint64
+	// the calls of Contains that found the element, and the ones that didn't
+	Hits   uint64
+	Misses uint64
+	// the elements deleted once they expired, the lazily expired ones
+	// are only counted when they're deleted
+	Expirations uint64
+	// the elements evicted because the set was full
+	Evictions uint64
+	// the batches of expired elements deleted under the lock,
+	// and the time the lock was held for them
+	Cleanups     uint64
+	CleanupPause time.Duration
+}
+
+// statsCounters are the counters behind Stats.
+type statsCounters struct {
+	adds        atomic.Uint64
+	removes     atomic.Uint64
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	expirations atomic.Uint64
+	evictions   atomic.Uint64
+	cleanups    atomic.Uint64
+	pauseNanos  atomic.Int64
+}
+
+
+// Counts the operations on the set, see Stats.
+// The counters are atomic, so they cost a few nanoseconds per operation.
+func WithStats() Option {
+	return func(c *config) {
+		c.countStats = true
+	}
+}
+
+
+// Returns the ratio of the hits to the calls of Contains, 0 if there is none.
+func(s Stats) HitRatio() float64 {
+	if s.Hits + s.Misses == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Hits + s.Misses)
+}
+
+
+// Returns the counters of the set,
+// which are all zero unless it's created with WithStats.
+// The counters are read one by one, so they can be off by the operations in flight.
+func(es *Set[T]) Stats() Stats {
+	c := es.stats
+	if c == nil {
+		return Stats{}
+	}
+
+	return Stats{
+		Adds:         c.adds.Load(),
+		Removes:      c.removes.Load(),
+		Hits:         c.hits.Load(),
+		Misses:       c.misses.Load(),
+		Expirations:  c.expirations.Load(),
+		Evictions:    c.evictions.Load(),
+		Cleanups:     c.cleanups.Load(),
+		CleanupPause: time.Duration(c.pauseNanos.Load()),
+	}
+}
+
+
+// Counts an element that left the set for the reason.
+func(c *statsCounters) left(reason EvictReason) {
+	switch reason {
+	case Expired:
+		c.expirations.Add(1)
+	case Evicted:
+		c.evictions.Add(1)
+	case Removed:
+		c.removes.Add(1)
+	}
+}
+
+
+// Counts a batch of the cleanup that has held the lock since start,
+// it must be called before the lock is released.
+func(es *Set[T]) countPause(start time.Time) {
+	if es.stats != nil {
+		es.stats.cleanups.Add(1)
+		es.stats.pauseNanos.Add(int64(time.Since(start)))
+	}
+}
+
+
+// Counts a call of Contains or of its variants if the set has stats,
+// and returns isExist.
+func(es *Set[T]) countLookup(isExist bool) bool {
+	if es.stats != nil {
+		es.stats.lookup(isExist)
+	}
+
+	return isExist
+}
+
+
+// Counts a call of Contains.
+func(c *statsCounters) lookup(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
@@ -0,0 +1,62 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	es := NewSet[int](WithStats(), WithMaxSize(2))
+	es.Add(1)
+	es.Add(2)
+	es.Add(3)
+	es.Contains(3)
+	es.Contains(1)
+	es.Contains(9)
+	es.Remove(3)
+	es.AddWithExpire(5, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	es.Size()
+
+	stats := es.Stats()
+	want := Stats{Adds: 4, Removes: 1, Hits: 1, Misses: 2, Expirations: 1, Evictions: 1}
+	stats.Cleanups, stats.CleanupPause = 0, 0
+	if stats != want {
+		t.Fatalf("got %+v, want %+v", stats, want)
+	}
+	if ratio := stats.HitRatio(); ratio < 0.33 || ratio > 0.34 {
+		t.Fatalf("hit ratio = %v, want 1/3", ratio)
+	}
+
+	if NewSet[int]().Stats() != (Stats{}) {
+		t.Fatal("set without WithStats counts")
+	}
+
+	sharded := NewSharded[int](WithStats(), WithShards(4))
+	sharded.Add(1)
+	sharded.Contains(1)
+	if stats := sharded.Stats(); stats.Adds != 1 || stats.Hits != 1 {
+		t.Fatalf("sharded stats = %+v, want an add and a hit", stats)
+	}
+}
+
+
+func TestCleanupPause(t *testing.T) {
+	es := NewSet[int](WithStats(), WithMaxSize(10))
+	for i := 0; i < 5; i++ {
+		es.AddWithExpire(i, time.Nanosecond)
+	}
+	time.Sleep(time.Millisecond)
+	es.Size()
+
+	stats := es.Stats()
+	if stats.Cleanups == 0 || stats.CleanupPause <= 0 || stats.Expirations != 5 {
+		t.Fatalf("got %+v, want the cleanup counted", stats)
+	}
+	if es.MaxSize() != 10 {
+		t.Fatalf("max size = %d, want 10", es.MaxSize())
+	}
+	if sharded := NewSharded[int](WithShards(4), WithMaxSize(100)); sharded.MaxSize() != 100 {
+		t.Fatalf("max size of the sharded set = %d, want 100", sharded.MaxSize())
+	}
+}
@@ -0,0 +1,224 @@
+package eset
+
+import (
+	"sync"
+	"time"
+)
+
+// Store persists the elements of a set, e.g. on disk,
+// with the time they expire at, which is zero if they have no ttl.
+// It's keyed by the elements, not by the keys of WithKeyFunc.
+type Store[T comparable] interface {
+	// ok is false if the element is not stored.
+	Get(elem T) (deadline time.Time, ok bool, err error)
+	Put(elem T, deadline time.Time) error
+	Delete(elem T) error
+	// Calls fn with each stored element until it returns false.
+	Iterate(fn func(elem T, deadline time.Time) bool) error
+}
+
+
+// Backs the set with a store, see Store.
+// Every change of the set is written to the store while holding the lock,
+// so a slow store holds up the other callers of the set,
+// unless the set has WithWriteBehind,
+// and Contains looks up the store for the elements not in memory,
+// loading them back into the set.
+// The elements evicted because the set is full stay in the store,
+// so with WithMaxSize the set is a cache of a store larger than the memory.
+// Size, GetAll and the set operations only see the elements in memory.
+// The errors of the store are reported by StoreErr.
+// It panics when the set is created if the elements of the store are not of the type of the set.
+func WithStore[T comparable](store Store[T]) Option {
+	return func(c *config) {
+		c.backend = store
+	}
+}
+
+
+// Returns the last error of the store of the set, nil if there is none.
+func(es *Set[T]) StoreErr() error {
+	if err := es.storeErr.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
+
+
+func(es *Set[T]) storeFailed(err error) {
+	if err != nil {
+		es.storeErr.Store(&err)
+	}
+}
+
+
+// Writes a change of the set to the store,
+// it must be called while holding the write lock,
+// so that the store sees the changes in the order of the set.
+func(es *Set[T]) mirror(key T, b base, isDeleted bool) {
+	if es.unmirrored {
+		return
+	}
+
+	elem := es.elemOf(key, b)
+	if es.rewritten != nil && !isDeleted {
+		es.rewritten[elem] = struct{}{}
+	}
+	if es.writeBehind != nil {
+		es.writeBehind.queue(elem, storeOp{deadline: b.expireTime(), isDeleted: isDeleted})
+		return
+	}
+	if isDeleted {
+		es.storeFailed(es.store.Delete(elem))
+	} else {
+		es.storeFailed(es.store.Put(elem, b.expireTime()))
+	}
+}
+
+
+// Clears the store once the lock is released,
+// it must be called while holding the write lock.
+// The store is not walked here, so that the lock isn't held for it.
+func(es *Set[T]) clearStore() {
+	if es.writeBehind != nil {
+		es.writeBehind.reset()
+	}
+
+	es.storeCleared = true
+	es.sweeps++
+	// the elements written before are the ones to delete
+	es.rewritten = make(map[T]struct{})
+}
+
+
+// Deletes the elements of the store that the set had when it's cleared.
+// The store is walked without the lock, then the elements are deleted
+// holding the write lock, like the other writes to the store,
+// except the ones written since the set is cleared.
+// A shard of a ShardedSet only deletes its own elements,
+// since the shards share the store.
+func(es *Set[T]) sweepStore() {
+	var elems []T
+	err := es.store.Iterate(func(elem T, _ time.Time) bool {
+		if es.owns == nil || es.owns(elem) {
+			elems = append(elems, elem)
+		}
+		return true
+	})
+	es.storeFailed(err)
+
+	es.lock()
+	for _, elem := range elems {
+		if _, ok := es.rewritten[elem]; !ok {
+			es.storeFailed(es.store.Delete(elem))
+		}
+	}
+	es.sweeps--
+	if es.sweeps == 0 {
+		es.rewritten = nil
+	}
+	es.unlock()
+}
+
+
+// Loads an element that is not in memory from the store.
+// Returns false if the store doesn't have it or it has expired.
+func(es *Set[T]) loadFromStore(elem T) bool {
+	deadline, ok, err := es.storeGet(elem)
+	if err != nil {
+		es.storeFailed(err)
+		return false
+	}
+	if !ok || !deadline.IsZero() && !deadline.After(es.now()) {
+		return false
+	}
+
+	b := base{expireAt: unixNano(deadline)}
+	if b.hasTTL() {
+		b.ttl = deadline.Sub(es.now())
+	}
+
+	es.lock()
+	if _, ok := es.rewritten[elem]; es.rewritten != nil && !ok {
+		// the set is cleared, but the store isn't swept yet
+		es.unlock()
+		return false
+	}
+	if _, isExist := es.elems[es.keyOf(elem)]; !isExist {
+		// it's already in the store
+		es.unmirrored = true
+		es.add(elem, b)
+		es.unmirrored = false
+	}
+	es.unlock()
+	return true
+}
+
+
+// Returns the element from the changes not written yet if it's there,
+// otherwise from the store.
+func(es *Set[T]) storeGet(elem T) (time.Time, bool, error) {
+	es.rlock()
+	wb := es.writeBehind
+	es.runlock()
+
+	if wb != nil {
+		if op, ok := wb.pending(elem); ok {
+			return op.deadline, !op.isDeleted, nil
+		}
+	}
+
+	return es.store.Get(elem)
+}
+
+
+// MemoryStore is a Store that keeps the elements in a map,
+// e.g. for tests of the code using a Store.
+type MemoryStore[T comparable] struct {
+	mutex sync.RWMutex
+	elems map[T]time.Time
+}
+
+
+func NewMemoryStore[T comparable]() *MemoryStore[T] {
+	return &MemoryStore[T]{elems: make(map[T]time.Time)}
+}
+
+
+func(s *MemoryStore[T]) Get(elem T) (time.Time, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	deadline, ok := s.elems[elem]
+	return deadline, ok, nil
+}
+
+
+func(s *MemoryStore[T]) Put(elem T, deadline time.Time) error {
+	s.mutex.Lock()
+	s.elems[elem] = deadline
+	s.mutex.Unlock()
+	return nil
+}
+
+
+func(s *MemoryStore[T]) Delete(elem T) error {
+	s.mutex.Lock()
+	delete(s.elems, elem)
+	s.mutex.Unlock()
+	return nil
+}
+
+
+// The store is read locked while fn runs.
+func(s *MemoryStore[T]) Iterate(fn func(elem T, deadline time.Time) bool) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for elem, deadline := range s.elems {
+		if !fn(elem, deadline) {
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	store := NewMemoryStore[int]()
+	es := NewSet[int](WithStore[int](store), WithMaxSize(2))
+	es.Add(1)
+	es.AddWithExpire(2, time.Hour)
+	es.Add(3)
+	es.Remove(3)
+
+	if _, ok, _ := store.Get(3); ok {
+		t.Fatal("removed element is still stored")
+	}
+	if deadline, ok, _ := store.Get(2); !ok || deadline.IsZero() {
+		t.Fatalf("2 is stored with %v, %v, want its deadline", deadline, ok)
+	}
+
+	// 1 is evicted from memory but stays in the store
+	es.Add(4)
+	if es.Size() != 2 {
+		t.Fatalf("size = %d, want 2", es.Size())
+	}
+	if _, ok, _ := store.Get(1); !ok {
+		t.Fatal("evicted element is deleted from the store")
+	}
+	if !es.Contains(1) {
+		t.Fatal("evicted element isn't loaded back from the store")
+	}
+
+	store.Put(5, time.Now().Add(-time.Second))
+	if es.Contains(5) {
+		t.Fatal("expired element is loaded from the store")
+	}
+
+	es.Clear()
+	n := 0
+	store.Iterate(func(int, time.Time) bool {
+		n++
+		return true
+	})
+	if n != 0 || es.StoreErr() != nil {
+		t.Fatalf("store has %d elements after Clear, error %v", n, es.StoreErr())
+	}
+}
+
+
+func TestShardedStoreClear(t *testing.T) {
+	store := NewMemoryStore[int]()
+	s := NewSharded[int](WithStore[int](store), WithShards(4))
+	for i := 0; i < 100; i++ {
+		s.Add(i)
+	}
+
+	// a shard only deletes its own elements of the store
+	s.shards[0].Clear()
+	for i := 0; i < 100; i++ {
+		if _, ok, _ := store.Get(i); ok != (s.index(i) != 0) {
+			t.Fatalf("%d is stored = %v after its shard %d is cleared", i, ok, s.index(i))
+		}
+	}
+
+	// the elements added before the store is swept are kept,
+	// and the cleared ones aren't loaded back from it
+	es := s.shards[1]
+	var added, cleared int
+	for i := 0; i < 100; i++ {
+		if s.index(i) == 1 {
+			added, cleared = cleared, i
+		}
+	}
+	es.lock()
+	es.gen++
+	es.init()
+	// the sweep is left to the test
+	es.storeCleared = false
+	es.unlock()
+	es.Add(added)
+	if es.Contains(cleared) {
+		t.Fatalf("%d is loaded back before the sweep", cleared)
+	}
+	es.sweepStore()
+	if _, ok, _ := store.Get(added); !ok || !es.Contains(added) {
+		t.Fatalf("%d is deleted by the sweep", added)
+	}
+	if _, ok, _ := store.Get(cleared); ok || s.Contains(cleared) {
+		t.Fatalf("%d is kept after the shard is cleared", cleared)
+	}
+
+	s.Clear()
+	store.Iterate(func(elem int, _ time.Time) bool {
+		t.Fatalf("%d is stored after Clear", elem)
+		return false
+	})
+	if s.Size() != 0 {
+		t.Fatalf("size = %d, want 0", s.Size())
+	}
+}
+
+
+func TestStoreType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("store of another type doesn't panic")
+		}
+	}()
+	NewSet[int](WithStore[string](NewMemoryStore[string]()))
+}
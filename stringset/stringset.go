@@ -0,0 +1,22 @@
+// Package stringset provides an expirable set of strings.
+// The elements are kept in a map[string] directly,
+// so they are not boxed into interface{} like eset.ExpirableSet does,
+// and Add and Contains don't allocate.
+package stringset
+
+import "github.com/ichxxx/eset"
+
+type Set = eset.Set[string]
+
+
+// Returns a set with the options, see eset.Option.
+func New(opts ...eset.Option) *Set {
+	return eset.NewSet[string](opts...)
+}
+
+
+// Pre-sizes the set to hold capacity elements,
+// see eset.WithCapacity.
+func NewWithCapacity(capacity int, opts ...eset.Option) *Set {
+	return eset.NewSet[string](append([]eset.Option{eset.WithCapacity(capacity)}, opts...)...)
+}
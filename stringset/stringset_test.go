@@ -0,0 +1,55 @@
+package stringset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ichxxx/eset"
+)
+
+func TestSet(t *testing.T) {
+	es := New()
+	es.Add("foo")
+	es.AddWithExpire("bar", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if !es.Contains("foo") || es.Contains("bar") || es.Size() != 1 {
+		t.Fatalf("has %v, want [foo]", es.GetAll())
+	}
+
+	sized := NewWithCapacity(100)
+	sized.Add("foo")
+	if !sized.Equal(es) {
+		t.Fatalf("has %v, want [foo]", sized.GetAll())
+	}
+}
+
+
+func TestContainsAllocs(t *testing.T) {
+	es := New()
+	key := string([]byte("hello-world-key"))
+	es.Add(key)
+
+	if n := testing.AllocsPerRun(100, func() { es.Contains(key) }); n != 0 {
+		t.Fatalf("Contains allocates %v times", n)
+	}
+}
+
+
+func TestOptions(t *testing.T) {
+	es := New(eset.WithMaxSize(2))
+	es.Add("a")
+	es.Add("b")
+	es.Add("c")
+	if es.Size() != 2 || es.Contains("a") {
+		t.Fatalf("has %v, want [b c]", es.GetAll())
+	}
+
+	sized := NewWithCapacity(100, eset.WithMaxSize(2))
+	sized.Add("a")
+	sized.Add("b")
+	sized.Add("c")
+	if sized.Size() != 2 || sized.Contains("a") {
+		t.Fatalf("has %v, want [b c]", sized.GetAll())
+	}
+}
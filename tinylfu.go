@@ -0,0 +1,135 @@
+package eset
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+// tinyLFU is an admission filter in front of an eviction policy.
+// It estimates how often each element is seen with a count-min sketch,
+// and only admits a new element to a full set
+// if it's seen more often than the element that would be evicted for it,
+// so that elements seen once don't push out frequently seen ones.
+type tinyLFU[T comparable] struct {
+	evictor[T]
+	mutex  sync.Mutex
+	sketch *countMinSketch[T]
+}
+
+// admitter decides whether a new element
+// is worth evicting another one for.
+type admitter[T comparable] interface {
+	admit(candidate, victim T) bool
+}
+
+
+func newTinyLFU[T comparable](policy evictor[T], size int) *tinyLFU[T] {
+	return &tinyLFU[T]{
+		evictor: policy,
+		sketch:  newCountMinSketch[T](size),
+	}
+}
+
+
+func(t *tinyLFU[T]) add(key T) {
+	t.record(key)
+	t.evictor.add(key)
+}
+
+
+func(t *tinyLFU[T]) access(key T) {
+	t.record(key)
+	t.evictor.access(key)
+}
+
+
+func(t *tinyLFU[T]) record(key T) {
+	t.mutex.Lock()
+	t.sketch.increment(key)
+	t.mutex.Unlock()
+}
+
+
+func(t *tinyLFU[T]) admit(candidate, victim T) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.sketch.estimate(candidate) > t.sketch.estimate(victim)
+}
+
+
+// countMinSketch counts with 4 rows of saturating counters,
+// the counters are halved once there have been
+// 10 increments per counter of a row, so old counts fade away.
+type countMinSketch[T comparable] struct {
+	seed     maphash.Seed
+	rows     [4][]uint8
+	mask     uint64
+	added    int
+	resetAt  int
+}
+
+const sketchMaxCount = 15
+
+
+func newCountMinSketch[T comparable](size int) *countMinSketch[T] {
+	width := 64
+	for width < size {
+		width <<= 1
+	}
+
+	cms := &countMinSketch[T]{
+		seed:    maphash.MakeSeed(),
+		mask:    uint64(width - 1),
+		resetAt: 10 * width,
+	}
+	for i := range cms.rows {
+		cms.rows[i] = make([]uint8, width)
+	}
+
+	return cms
+}
+
+
+func(cms *countMinSketch[T]) index(hash uint64, row int) uint64 {
+	// double hashing
+	return (hash + uint64(row) * (hash >> 32 | 1)) & cms.mask
+}
+
+
+func(cms *countMinSketch[T]) increment(key T) {
+	hash := maphash.Comparable(cms.seed, key)
+	for i := range cms.rows {
+		counter := &cms.rows[i][cms.index(hash, i)]
+		if *counter < sketchMaxCount {
+			*counter++
+		}
+	}
+
+	cms.added++
+	if cms.added >= cms.resetAt {
+		cms.reset()
+	}
+}
+
+
+func(cms *countMinSketch[T]) estimate(key T) uint8 {
+	hash := maphash.Comparable(cms.seed, key)
+	min := uint8(sketchMaxCount)
+	for i := range cms.rows {
+		if counter := cms.rows[i][cms.index(hash, i)]; counter < min {
+			min = counter
+		}
+	}
+
+	return min
+}
+
+
+func(cms *countMinSketch[T]) reset() {
+	cms.added = 0
+	for i := range cms.rows {
+		for j := range cms.rows[i] {
+			cms.rows[i][j] >>= 1
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package eset
+
+import (
+	"context"
+	"time"
+)
+
+// the longest wait between the attempts to get the lock
+const maxLockWait = time.Millisecond
+
+
+// Same as Add, but gives up if the lock is held by others.
+// Returns false if the element is not added.
+func(es *Set[T]) TryAdd(elem T) bool {
+	if !es.tryLock() {
+		return false
+	}
+
+	es.add(elem, es.defaultBase())
+	es.unlock()
+	return true
+}
+
+
+// Same as Contains, but gives up if the lock is held by others.
+// ok is false if it gives up.
+func(es *Set[T]) TryContains(elem T) (isExist, ok bool) {
+	key := es.keyOf(elem)
+	if es.sliding {
+		if !es.tryLock() {
+			return false, false
+		}
+		defer es.unlock()
+		return es.countLookup(es.renew(key)), true
+	}
+
+	if es.canLookup() {
+		return es.Contains(elem), true
+	}
+
+	if !es.tryRLock() {
+		return false, false
+	}
+
+	isExist, _ = es.has(key)
+	es.runlock()
+	return es.countLookup(isExist), true
+}
+
+
+// Same as Add, but gives up once the context is done
+// if the lock can't be obtained by then.
+// Returns the error of the context if it gives up.
+func(es *Set[T]) AddContext(ctx context.Context, elem T) error {
+	if err := lockContext(ctx, es.tryLock); err != nil {
+		return err
+	}
+
+	es.add(elem, es.defaultBase())
+	es.unlock()
+	return nil
+}
+
+
+// Same as Contains, but gives up once the context is done
+// if the lock can't be obtained by then.
+// Returns the error of the context if it gives up.
+func(es *Set[T]) ContainsContext(ctx context.Context, elem T) (bool, error) {
+	key := es.keyOf(elem)
+	if es.sliding {
+		if err := lockContext(ctx, es.tryLock); err != nil {
+			return false, err
+		}
+		defer es.unlock()
+		return es.countLookup(es.renew(key)), nil
+	}
+
+	if es.canLookup() {
+		return es.Contains(elem), nil
+	}
+
+	if err := lockContext(ctx, es.tryRLock); err != nil {
+		return false, err
+	}
+
+	isExist, _ := es.has(key)
+	es.runlock()
+	return es.countLookup(isExist), nil
+}
+
+
+// Tries to get a lock until the context is done,
+// waiting longer and longer between the attempts.
+func lockContext(ctx context.Context, tryLock func() bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for wait := time.Microsecond; !tryLock(); {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if wait < maxLockWait {
+			wait *= 2
+		}
+	}
+
+	return nil
+}
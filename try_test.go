@@ -0,0 +1,62 @@
+package eset
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTry(t *testing.T) {
+	es := NewSet[int]()
+	if !es.TryAdd(1) {
+		t.Fatal("TryAdd gave up on a free lock")
+	}
+	if isExist, ok := es.TryContains(1); !ok || !isExist {
+		t.Fatal("TryContains gave up on a free lock")
+	}
+
+	es.mutex.Lock()
+	if es.TryAdd(2) {
+		t.Fatal("TryAdd didn't give up on a held lock")
+	}
+	if _, ok := es.TryContains(1); ok {
+		t.Fatal("TryContains didn't give up on a held lock")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Millisecond)
+	defer cancel()
+	if err := es.AddContext(ctx, 3); err != context.DeadlineExceeded {
+		t.Fatalf("AddContext error = %v, want context.DeadlineExceeded", err)
+	}
+
+	go func() {
+		time.Sleep(2 * time.Millisecond)
+		es.mutex.Unlock()
+	}()
+	if isExist, err := es.ContainsContext(context.Background(), 1); !isExist || err != nil {
+		t.Fatalf("ContainsContext = %v, %v after the lock is released", isExist, err)
+	}
+	if es.Contains(2) || es.Contains(3) {
+		t.Fatal("gave up but added the element")
+	}
+}
+
+
+func TestTryStats(t *testing.T) {
+	for _, sliding := range []bool{false, true} {
+		opts := []Option{WithStats()}
+		if sliding {
+			opts = append(opts, WithSlidingExpiration())
+		}
+		es := NewSet[int](opts...)
+		es.AddWithExpire(1, time.Hour)
+
+		es.TryContains(1)
+		es.TryContains(2)
+		es.ContainsContext(context.Background(), 1)
+		es.ContainsContext(context.Background(), 2)
+		if stats := es.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+			t.Fatalf("sliding %v: hits %d, misses %d, want 2 and 2", sliding, stats.Hits, stats.Misses)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package eset
+
+// UnionView answers membership questions about the union of sets
+// by consulting them on demand, without building the union.
+// It reflects the later changes of the sets.
+type UnionView[T comparable] struct {
+	sets []*Set[T]
+}
+
+// IntersectView is the same as UnionView for the intersection of sets.
+type IntersectView[T comparable] struct {
+	sets []*Set[T]
+}
+
+
+func NewUnionView[T comparable](sets ...*Set[T]) *UnionView[T] {
+	return &UnionView[T]{sets: sets}
+}
+
+
+func NewIntersectView[T comparable](sets ...*Set[T]) *IntersectView[T] {
+	return &IntersectView[T]{sets: sets}
+}
+
+
+// Returns true if any of the sets has the element.
+func(v *UnionView[T]) Contains(elem T) bool {
+	for _, es := range v.sets {
+		if es.Contains(elem) {
+			return true
+		}
+	}
+
+	return false
+}
+
+
+// Do something for each element of the union once.
+// The sets are read one at a time, and no lock is held while the handler runs.
+func(v *UnionView[T]) ForEach(handler func(T)) {
+	var buf []T
+	for i, es := range v.sets {
+		buf = es.AppendTo(buf[:0])
+		for _, elem := range buf {
+			if !anyExists(v.sets[:i], elem) {
+				handler(elem)
+			}
+		}
+	}
+}
+
+
+// Returns true if all of the sets have the element,
+// false if there is no set.
+func(v *IntersectView[T]) Contains(elem T) bool {
+	for _, es := range v.sets {
+		if !es.Contains(elem) {
+			return false
+		}
+	}
+
+	return len(v.sets) > 0
+}
+
+
+// Do something for each element of the intersection.
+// The smallest set is walked, and no lock is held while the handler runs.
+func(v *IntersectView[T]) ForEach(handler func(T)) {
+	if len(v.sets) == 0 {
+		return
+	}
+
+	smallest := v.sets[0]
+	for _, es := range v.sets[1:] {
+		if es.Len() < smallest.Len() {
+			smallest = es
+		}
+	}
+
+	for _, elem := range smallest.AppendTo(nil) {
+		isCommon := true
+		for _, es := range v.sets {
+			if es != smallest && !es.exists(elem) {
+				isCommon = false
+				break
+			}
+		}
+
+		if isCommon {
+			handler(elem)
+		}
+	}
+}
+
+
+func anyExists[T comparable](sets []*Set[T], elem T) bool {
+	for _, es := range sets {
+		if es.exists(elem) {
+			return true
+		}
+	}
+
+	return false
+}
+
+
+// Returns true if the unexpired element is in the set,
+// unlike Contains, it never renews the ttl nor counts as an access.
+func(es *Set[T]) exists(elem T) bool {
+	key := es.keyOf(elem)
+	if es.canLookup() {
+		base, isExist := es.lookup(key)
+		return isExist && !es.isExpired(base)
+	}
+
+	es.rlock()
+	base, isExist := es.elems[key]
+	isExist = isExist && !es.isExpired(base)
+	es.runlock()
+	return isExist
+}
@@ -0,0 +1,57 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnionView(t *testing.T) {
+	one := setOf(1, 2, 3)
+	other := setOf(3, 4)
+	other.AddWithExpire(5, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	view := NewUnionView(one, other)
+	var elems []int
+	view.ForEach(func(elem int) {
+		elems = append(elems, elem)
+	})
+	if got := sortedInts(elems); !equalInts(got, []int{1, 2, 3, 4}) {
+		t.Fatalf("ForEach walked %v, want [1 2 3 4]", got)
+	}
+	if !view.Contains(4) || view.Contains(5) || view.Contains(9) {
+		t.Fatal("wrong Contains")
+	}
+
+	// the view is lazy
+	other.Add(9)
+	if !view.Contains(9) {
+		t.Fatal("view doesn't see the added element")
+	}
+}
+
+
+func TestIntersectView(t *testing.T) {
+	one := setOf(1, 2, 3)
+	other := setOf(3, 4)
+
+	view := NewIntersectView(one, other)
+	var elems []int
+	view.ForEach(func(elem int) {
+		elems = append(elems, elem)
+	})
+	if !equalInts(elems, []int{3}) {
+		t.Fatalf("ForEach walked %v, want [3]", elems)
+	}
+	if !view.Contains(3) || view.Contains(1) {
+		t.Fatal("wrong Contains")
+	}
+	if NewIntersectView[int]().Contains(1) {
+		t.Fatal("intersection of no set has an element")
+	}
+
+	other.Add(1)
+	if !view.Contains(1) {
+		t.Fatal("view doesn't see the added element")
+	}
+}
@@ -0,0 +1,217 @@
+package eset
+
+import (
+	"math"
+	"time"
+)
+
+// timingWheel is a hierarchical timing wheel.
+// Level 0 has a slot for each tick,
+// and each slot of level n covers a whole round of level n-1.
+// Items are put into the lowest level whose round can reach them,
+// and are moved down a level when the slot they are in comes,
+// so pushing an item and expiring it are both O(1) amortized.
+// Items expire at the end of the tick they fall in,
+// never earlier than their expiration time.
+type timingWheel[T comparable] struct {
+	tick   int64
+	slots  int64
+	levels [][][]expireItem[T]
+	// the items in each level
+	sizes []int
+	// the next tick to be processed
+	cur   int64
+	count int
+}
+
+
+func newTimingWheel[T comparable](tick time.Duration, slots, levels int) *timingWheel[T] {
+	tw := &timingWheel[T]{
+		tick:  int64(tick),
+		slots: int64(slots),
+		cur:   time.Now().UnixNano() / int64(tick),
+	}
+	tw.levels = make([][][]expireItem[T], levels)
+	tw.sizes = make([]int, levels)
+	for i := range tw.levels {
+		tw.levels[i] = make([][]expireItem[T], slots)
+	}
+
+	return tw
+}
+
+
+func(tw *timingWheel[T]) push(key T, b base) {
+	tw.insert(expireItem[T]{key: key, base: b})
+	tw.count++
+}
+
+
+func(tw *timingWheel[T]) insert(item expireItem[T]) {
+	at := item.base.expireAt / tw.tick
+	if at < tw.cur {
+		at = tw.cur
+	}
+
+	diff := at - tw.cur
+	span := int64(1)
+	level := 0
+	for ; level < len(tw.levels)-1; level++ {
+		if diff < span * tw.slots {
+			break
+		}
+		span *= tw.slots
+	}
+
+	slot := (at / span) % tw.slots
+	tw.levels[level][slot] = append(tw.levels[level][slot], item)
+	tw.sizes[level]++
+}
+
+
+// The limit is checked between the ticks,
+// so it may be exceeded by the items of a single tick.
+// The ticks that can't have items are skipped,
+// so that a wheel that hasn't been popped for long catches up quickly.
+func(tw *timingWheel[T]) popExpired(now time.Time, limit int, fn func(item expireItem[T])) bool {
+	end := now.UnixNano() / tw.tick
+	if tw.count == 0 {
+		if end > tw.cur {
+			tw.cur = end
+		}
+		return false
+	}
+
+	popped := 0
+	for tw.cur < end {
+		if limit > 0 && popped >= limit {
+			return true
+		}
+
+		if next := tw.nextBusyTick(); next > tw.cur {
+			tw.cur = min(next, end)
+			continue
+		}
+
+		// move the items of the slots that come down a level,
+		// higher levels first so that their items can be moved
+		// again by the lower levels in the same tick
+		for level := len(tw.levels)-1; level > 0; level-- {
+			span := pow(tw.slots, level)
+			if tw.cur % span != 0 {
+				continue
+			}
+
+			slot := (tw.cur / span) % tw.slots
+			items := tw.levels[level][slot]
+			tw.levels[level][slot] = nil
+			tw.sizes[level] -= len(items)
+			for _, item := range items {
+				tw.insert(item)
+			}
+		}
+
+		slot := tw.cur % tw.slots
+		items := tw.levels[0][slot]
+		tw.levels[0][slot] = nil
+		tw.sizes[0] -= len(items)
+		tw.count -= len(items)
+		popped += len(items)
+		for _, item := range items {
+			fn(item)
+		}
+		tw.cur++
+	}
+
+	return false
+}
+
+
+// Returns the first tick from the current one that has work to do,
+// which is the current tick if level 0 has items,
+// otherwise the next tick the lowest level with items moves them down at,
+// the levels below it have nothing to pop until then.
+func(tw *timingWheel[T]) nextBusyTick() int64 {
+	span := int64(1)
+	for level := range tw.levels {
+		if tw.sizes[level] > 0 {
+			return (tw.cur + span - 1) / span * span
+		}
+		span *= tw.slots
+	}
+
+	return math.MaxInt64
+}
+
+
+// Timing wheels are not ordered within the slots,
+// so it scans all the items.
+func(tw *timingWheel[T]) due(before time.Time, fn func(item expireItem[T])) {
+	for _, slots := range tw.levels {
+		for _, items := range slots {
+			for _, item := range items {
+				if item.base.expireAt < before.UnixNano() {
+					fn(item)
+				}
+			}
+		}
+	}
+}
+
+
+// Timing wheels are not ordered within the slots,
+// so it scans all the items.
+func(tw *timingWheel[T]) first(valid func(item expireItem[T]) bool) (first expireItem[T], ok bool) {
+	for _, slots := range tw.levels {
+		for _, items := range slots {
+			for _, item := range items {
+				if valid(item) && (!ok || item.base.expireAt < first.base.expireAt) {
+					first, ok = item, true
+				}
+			}
+		}
+	}
+
+	return first, ok
+}
+
+
+func(tw *timingWheel[T]) len() int {
+	return tw.count
+}
+
+
+func(tw *timingWheel[T]) clone() expirer[T] {
+	newTw := *tw
+	newTw.sizes = append([]int(nil), tw.sizes...)
+	newTw.levels = make([][][]expireItem[T], len(tw.levels))
+	for i, slots := range tw.levels {
+		newTw.levels[i] = make([][]expireItem[T], len(slots))
+		for j, items := range slots {
+			newTw.levels[i][j] = append([]expireItem[T](nil), items...)
+		}
+	}
+
+	return &newTw
+}
+
+
+func(tw *timingWheel[T]) reset() {
+	for _, slots := range tw.levels {
+		for i := range slots {
+			slots[i] = nil
+		}
+	}
+	clear(tw.sizes)
+	tw.count = 0
+}
+
+
+func pow(x int64, n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= x
+	}
+
+	return result
+}
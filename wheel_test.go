@@ -0,0 +1,144 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimingWheelCascade(t *testing.T) {
+	// 4 slots of 3 levels reach 64 ticks,
+	// the items beyond that go round the top level
+	tests := []struct {
+		name  string
+		ticks int64
+		level int
+	}{
+		{"current tick", 0, 0},
+		{"last slot of level 0", 3, 0},
+		{"first slot of level 1", 4, 1},
+		{"last slot of level 1", 15, 1},
+		{"first slot of level 2", 16, 2},
+		{"last slot of level 2", 63, 2},
+		{"beyond the top level", 100, 2},
+		{"unaligned", 37, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tw := newTimingWheel[int](time.Millisecond, 4, 3)
+			// start in the middle of a round of every level
+			start := int64(1000 * 64 + 21)
+			tw.cur = start
+			pushAt(tw, 1, start + tt.ticks)
+			if got := levelOf(tw, 1); got != tt.level {
+				t.Fatalf("pushed to level %d, want %d", got, tt.level)
+			}
+
+			for tick := start; tick <= start + tt.ticks; tick++ {
+				popped := popAt(tw, tick + 1)
+				if tick < start + tt.ticks && len(popped) > 0 {
+					t.Fatalf("popped at tick %d, want %d", tick - start, tt.ticks)
+				}
+				if tick == start + tt.ticks && len(popped) != 1 {
+					t.Fatalf("not popped at tick %d", tt.ticks)
+				}
+			}
+
+			if tw.len() != 0 {
+				t.Fatalf("len = %d after popping, want 0", tw.len())
+			}
+		})
+	}
+}
+
+
+func TestTimingWheelPopLimit(t *testing.T) {
+	tw := newTimingWheel[int](time.Millisecond, 4, 3)
+	start := tw.cur
+	for i := 0; i < 40; i++ {
+		pushAt(tw, i, start + int64(i))
+	}
+
+	now := time.Unix(0, (start + 64) * tw.tick)
+	popped := 0
+	more := tw.popExpired(now, 10, func(expireItem[int]) {
+		popped++
+	})
+	if !more || popped != 10 {
+		t.Fatalf("popped %d with more = %v, want 10 with more", popped, more)
+	}
+
+	more = tw.popExpired(now, 0, func(expireItem[int]) {
+		popped++
+	})
+	if more || popped != 40 || tw.len() != 0 {
+		t.Fatalf("popped %d with more = %v and len %d, want all of them", popped, more, tw.len())
+	}
+}
+
+
+func TestTimingWheelIdleTicks(t *testing.T) {
+	// a nanosecond tick has far more ticks between the items
+	// than popExpired could visit one by one
+	tw := newTimingWheel[int](time.Nanosecond, 64, 4)
+	start := tw.cur
+	pushAt(tw, 1, start + 10000000)
+	pushAt(tw, 2, start + 100000000000)
+
+	if got := popAt(tw, start + 10000000); len(got) != 0 {
+		t.Fatalf("popped %v before the tick of 1 ended", got)
+	}
+	if got := popAt(tw, start + 10000001); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("popped %v, want [1]", got)
+	}
+	if got := popAt(tw, start + 100000000001); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("popped %v, want [2]", got)
+	}
+	if tw.len() != 0 {
+		t.Fatalf("len = %d, want 0", tw.len())
+	}
+}
+
+
+func TestTimingWheelPastItems(t *testing.T) {
+	tw := newTimingWheel[int](time.Millisecond, 4, 3)
+	start := tw.cur
+	pushAt(tw, 1, start - 100)
+
+	if popped := popAt(tw, start + 1); len(popped) != 1 {
+		t.Fatalf("popped %v, want the item in the past at the current tick", popped)
+	}
+}
+
+
+// Pushes the key to expire at the start of the tick.
+func pushAt(tw *timingWheel[int], key int, tick int64) {
+	tw.push(key, base{expireAt: tick * tw.tick})
+}
+
+
+// Returns the keys popped at the start of the tick.
+func popAt(tw *timingWheel[int], tick int64) []int {
+	var popped []int
+	tw.popExpired(time.Unix(0, tick * tw.tick), 0, func(item expireItem[int]) {
+		popped = append(popped, item.key)
+	})
+
+	return popped
+}
+
+
+// Returns the level the key is in, -1 if it's in none.
+func levelOf[T comparable](tw *timingWheel[T], key T) int {
+	for level, slots := range tw.levels {
+		for _, items := range slots {
+			for _, item := range items {
+				if item.key == key {
+					return level
+				}
+			}
+		}
+	}
+
+	return -1
+}
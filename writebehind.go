@@ -0,0 +1,149 @@
+package eset
+
+import (
+	"sync"
+	"time"
+)
+
+// storeOp is a change of an element waiting to be written to the store.
+type storeOp struct {
+	deadline  time.Time
+	isDeleted bool
+}
+
+// writeBehind queues the changes of a set for its store,
+// only the last change of each element is kept.
+// It has its own lock, so that the flusher never takes the lock of the set.
+type writeBehind[T comparable] struct {
+	mutex sync.Mutex
+	// signaled when a flush is done
+	flushed *sync.Cond
+	// the changes not taken by a flush yet
+	queued map[T]storeOp
+	// the changes being written by the flush in progress, nil if there is none
+	flushing map[T]storeOp
+	size     int
+	interval time.Duration
+	// wakes up the flusher when the queue is full
+	full chan struct{}
+}
+
+
+// Writes the changes to the store in the background
+// instead of while holding the lock, see WithStore.
+// The changes are queued, keeping only the last one of each element,
+// and written every interval, or once size elements are queued,
+// an interval less than or equal to 0 only writes them when the queue is full.
+// When the queue is full while a flush is still in progress,
+// the writers of the set wait for the flush.
+// The queue is flushed by Close, and the changes after it are written through.
+func WithWriteBehind(size int, interval time.Duration) Option {
+	return func(c *config) {
+		c.writeBehindSize = size
+		c.writeBehindInterval = interval
+	}
+}
+
+
+func newWriteBehind[T comparable](size int, interval time.Duration) *writeBehind[T] {
+	wb := &writeBehind[T]{
+		queued:   make(map[T]storeOp),
+		size:     size,
+		interval: interval,
+		full:     make(chan struct{}, 1),
+	}
+	wb.flushed = sync.NewCond(&wb.mutex)
+	return wb
+}
+
+
+func(wb *writeBehind[T]) queue(elem T, op storeOp) {
+	wb.mutex.Lock()
+	for len(wb.queued) >= wb.size && wb.flushing != nil {
+		wb.flushed.Wait()
+	}
+
+	wb.queued[elem] = op
+	if len(wb.queued) >= wb.size {
+		select {
+		case wb.full <- struct{}{}:
+		default:
+		}
+	}
+	wb.mutex.Unlock()
+}
+
+
+// Returns the change of the element that is not written yet.
+func(wb *writeBehind[T]) pending(elem T) (storeOp, bool) {
+	wb.mutex.Lock()
+	defer wb.mutex.Unlock()
+
+	if op, ok := wb.queued[elem]; ok {
+		return op, true
+	}
+	op, ok := wb.flushing[elem]
+	return op, ok
+}
+
+
+// Drops the queued changes, e.g. when the store is cleared,
+// after the flush in progress is done.
+func(wb *writeBehind[T]) reset() {
+	wb.mutex.Lock()
+	for wb.flushing != nil {
+		wb.flushed.Wait()
+	}
+	wb.queued = make(map[T]storeOp)
+	wb.mutex.Unlock()
+}
+
+
+func(es *Set[T]) runWriteBehind(wb *writeBehind[T]) {
+	// without an interval it's only flushed when it's full
+	var tick <-chan time.Time
+	if wb.interval > 0 {
+		ticker := time.NewTicker(wb.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+		case <-wb.full:
+		case <-es.stop:
+			return
+		}
+		es.flushStore(wb)
+	}
+}
+
+
+// Writes the queued changes to the store.
+func(es *Set[T]) flushStore(wb *writeBehind[T]) {
+	wb.mutex.Lock()
+	for wb.flushing != nil {
+		wb.flushed.Wait()
+	}
+	if len(wb.queued) == 0 {
+		wb.mutex.Unlock()
+		return
+	}
+	batch := wb.queued
+	wb.flushing, wb.queued = batch, make(map[T]storeOp)
+	wb.mutex.Unlock()
+
+	for elem, op := range batch {
+		if op.isDeleted {
+			es.storeFailed(es.store.Delete(elem))
+		} else {
+			es.storeFailed(es.store.Put(elem, op.deadline))
+		}
+	}
+
+	wb.mutex.Lock()
+	wb.flushing = nil
+	wb.flushed.Broadcast()
+	wb.mutex.Unlock()
+}
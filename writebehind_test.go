@@ -0,0 +1,94 @@
+package eset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteBehind(t *testing.T) {
+	store := NewMemoryStore[string]()
+	es := NewSet[string](WithStore[string](store), WithWriteBehind(3, time.Hour))
+	es.Add("a")
+	if _, ok, _ := store.Get("a"); ok {
+		t.Fatal("change is written through")
+	}
+
+	// a full queue is flushed without waiting for the interval
+	es.Add("b")
+	es.Add("c")
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok, _ := store.Get("c"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("full queue isn't flushed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// the pending delete hides the element in the store
+	es.Remove("a")
+	if es.Contains("a") {
+		t.Fatal("removed element is loaded back from the store")
+	}
+
+	es.Add("d")
+	if err := es.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := store.Get("d"); !ok {
+		t.Fatal("Close doesn't flush the queue")
+	}
+	if _, ok, _ := store.Get("a"); ok {
+		t.Fatal("Close doesn't flush the delete")
+	}
+}
+
+
+func TestWriteBehindConcurrent(t *testing.T) {
+	store := NewMemoryStore[int]()
+	es := NewSet[int](WithStore[int](store), WithWriteBehind(16, time.Millisecond))
+	done := make(chan bool)
+	for g := 0; g < 4; g++ {
+		go func(g int) {
+			for i := 0; i < 2000; i++ {
+				es.Add(g * 10000 + i)
+				if i % 3 == 0 {
+					es.Remove(g * 10000 + i)
+				}
+				es.Contains(g * 10000 + i - 1)
+			}
+			done <- true
+		}(g)
+	}
+	for g := 0; g < 4; g++ {
+		<-done
+	}
+	es.Close()
+
+	n := 0
+	store.Iterate(func(int, time.Time) bool {
+		n++
+		return true
+	})
+	if n != es.Size() {
+		t.Fatalf("store has %d elements, the set %d", n, es.Size())
+	}
+}
+
+
+func TestShardedWriteBehind(t *testing.T) {
+	store := NewMemoryStore[string]()
+	s := NewSharded[string](WithStore[string](store), WithWriteBehind(100, time.Hour))
+	s.AddAll("a", "b", "c")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, elem := range []string{"a", "b", "c"} {
+		if _, ok, _ := store.Get(elem); !ok {
+			t.Fatalf("Close doesn't flush %s of its shard", elem)
+		}
+	}
+}